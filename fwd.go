@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/lamhai1401/gologs/logger"
 	"github.com/lamhai1401/gologs/logs"
 	log "github.com/lamhai1401/gologs/logs"
 	"github.com/pion/rtp"
@@ -27,6 +28,10 @@ type Wrapper struct {
 	Type   string     `json:"type"`   // type off wrapper data - ok - ping - pong
 }
 
+// closedLog is the typed log of closed forwarders, the log of their stream is
+// forgotten on close and would be built again by each late message
+var closedLog = logs.NewTyped("")
+
 // Forwarder linter
 type Forwarder struct {
 	id          string                                  // stream id
@@ -35,6 +40,7 @@ type Forwarder struct {
 	handlers    map[string]func(wrapper *Wrapper) error // to save handler
 	actionChann chan *action                            // handle action add and remove, close
 	msgChann    chan *Wrapper
+	log         *logs.Typed // typed log of the stream, called on the packet path
 	mutex       sync.RWMutex
 }
 
@@ -47,6 +53,7 @@ func NewForwarder(id string) *Forwarder {
 		handlers:    make(map[string]func(wrapper *Wrapper) error),
 		isClosed:    false,
 		msgChann:    make(chan *Wrapper),
+		log:         logs.NewTyped(id),
 	}
 
 	f.serve()
@@ -104,13 +111,21 @@ func (f *Forwarder) addNewClient(clientID string, handler func(wrapper *Wrapper)
 	f.setClient(clientID, make(chan *Wrapper, 1000))
 	f.setHandler(clientID, handler)
 
-	go f.collectData(clientID)
+	// a panicking handler is restarted on the same data channel
+	chann := f.getData(clientID)
+	logs.Supervise(f.goName(clientID), func() {
+		f.collectData(clientID, chann)
+	})
+}
+
+// goName return the supervised goroutine name of a task of this forwarder
+func (f *Forwarder) goName(task string) string {
+	return fmt.Sprintf("fwd %s/%s", f.getID(), task)
 }
 
-func (f *Forwarder) collectData(clientID string) {
+func (f *Forwarder) collectData(clientID string, chann <-chan Wrapper) {
 	var handler func(w *Wrapper) error
 	var err error
-	chann := f.getData(clientID)
 
 	for {
 		if f.checkClose() {
@@ -125,12 +140,12 @@ func (f *Forwarder) collectData(clientID string) {
 
 		handler = f.getHandler(clientID)
 		if handler == nil {
-			f.info(fmt.Sprintf("%s handler is nil. Close for loop", clientID))
+			f.info("handler is nil. Close for loop", logger.String("client_id", clientID))
 			return
 		}
 
 		if err = handler(&w); err != nil {
-			log.Error(fmt.Sprintf("%s handler err: %v", clientID, err))
+			f.error("handler err", logger.String("client_id", clientID), logger.Err(err))
 			return
 		}
 
@@ -156,7 +171,7 @@ func (f *Forwarder) getData(clientID string) <-chan Wrapper {
 
 	chann := f.getClient(clientID)
 
-	go func() {
+	logs.Go(f.goName(clientID+"/data"), func() {
 		defer close(c)
 		for {
 			ctx, cancel = context.WithTimeout(parent, timeout)
@@ -179,7 +194,7 @@ func (f *Forwarder) getData(clientID string) <-chan Wrapper {
 			ctx = nil
 			cancel = nil
 		}
-	}()
+	})
 
 	return c
 }
@@ -215,7 +230,12 @@ func (f *Forwarder) transfer(fw *Forwarder) {
 			if ok {
 				fw.setClient(k, make(chan *Wrapper, 1000))
 				fw.setHandler(k, handler)
-				fw.collectData(k)
+
+				// a panicking handler is restarted on the same data channel
+				clientID, chann := k, fw.getData(k)
+				logs.Supervise(fw.goName(clientID), func() {
+					fw.collectData(clientID, chann)
+				})
 			}
 		}
 	}
@@ -244,18 +264,28 @@ func (f *Forwarder) close() {
 	if !f.checkClose() {
 		f.setClose(true)
 		f.closeClients()
-		f.info(fmt.Sprintf("%s forwader was closed", f.getID()))
+		f.info("forwader was closed")
+		log.Forget(f.getID())
 	}
 }
 
-// info to export log info
-func (f *Forwarder) info(v ...interface{}) {
-	log.Info(fmt.Sprintf("[%s] ", f.id), v)
+// info to export log info, with the stream_id field alert rules group by
+func (f *Forwarder) info(msg string, fields ...logger.Field) {
+	l := f.log
+	if f.checkClose() {
+		l = closedLog
+	}
+	l.Info(msg, append(fields, logger.String("stream_id", f.id))...)
 }
 
-// error to export error info
-func (f *Forwarder) error(v ...interface{}) {
-	log.Error(fmt.Sprintf("[%s] ", f.id), v)
+// error to export error info, dump the forwarder flight recorder
+// The caller is the line calling error, not this wrapper
+func (f *Forwarder) error(msg string, fields ...logger.Field) {
+	l := f.log
+	if f.checkClose() {
+		l = closedLog
+	}
+	l.Error(msg, append(fields, logger.String("stream_id", f.id), logger.Caller(1))...)
 }
 
 func (f *Forwarder) getClient(clientID string) chan *Wrapper {
@@ -335,7 +365,7 @@ func (f *Forwarder) getActionChann() chan *action {
 
 // Serve to run
 func (f *Forwarder) serve() {
-	go func() {
+	logs.Supervise(f.goName("forward"), func() {
 		for {
 			msg, open := <-f.msgChann
 			if !open || f.checkClose() {
@@ -344,9 +374,9 @@ func (f *Forwarder) serve() {
 			f.forward(msg)
 			msg = nil
 		}
-	}()
+	})
 
-	go func() {
+	logs.Supervise(f.goName("actions"), func() {
 		for {
 			action, open := <-f.actionChann
 			if !open || f.checkClose() {
@@ -366,12 +396,12 @@ func (f *Forwarder) serve() {
 				logs.Info("Nothing to do with this action: ", *action.action)
 			}
 		}
-	}()
+	})
 }
 
 func (f *Forwarder) forward(wrapper *Wrapper) {
 	if f.checkClose() {
-		f.info(f.getID(), " fwd was closed")
+		f.info("fwd was closed")
 		return
 	}
 
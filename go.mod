@@ -6,6 +6,7 @@ require (
 	github.com/davecgh/go-spew v1.1.1
 	github.com/kdar/factorlog v0.0.0-20140929220826-d5b6afb8b4fe
 	github.com/mattn/go-colorable v0.1.7 // indirect
+	github.com/mattn/go-isatty v0.0.12
 	github.com/mgutz/ansi v0.0.0-20200706080929-d51e80ef957d // indirect
 	github.com/pion/randutil v0.1.0 // indirect
 	github.com/pion/rtp v1.6.0
@@ -0,0 +1,209 @@
+package logger
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// normalizers replace variable parts of messages, in order
+var normalizers = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`\b[0-9A-Za-z]{27}\b`), "<ksuid>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]*[0-9][0-9a-fA-F]*[a-fA-F][0-9a-fA-F]*\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9A-Za-z_\-]*[A-Za-z][0-9A-Za-z_\-]*[0-9][0-9A-Za-z_\-]*\b`), "<id>"},
+	{regexp.MustCompile(`\b[0-9A-Za-z_\-]*[0-9][0-9A-Za-z_\-]*[A-Za-z][0-9A-Za-z_\-]*\b`), "<id>"},
+	{regexp.MustCompile(`\d+(\.\d+)*`), "<n>"},
+}
+
+// Normalize replace ids, numbers, ksuids and hex in a message so
+// messages of the same error look the same
+func Normalize(message string) string {
+	for _, n := range normalizers {
+		message = n.re.ReplaceAllString(message, n.repl)
+	}
+	return message
+}
+
+// Fingerprint return a short hash of a normalized message and its caller
+func Fingerprint(pattern, caller string) string {
+	sum := sha1.Sum([]byte(caller + "\x00" + pattern))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ErrorGroup is errors of the same fingerprint
+type ErrorGroup struct {
+	Fingerprint string
+	Pattern     string // normalized message
+	Caller      string
+	Count       int
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	Sample      string // last original message
+}
+
+// Aggregator group error entries by fingerprint and write
+// the groups seen in each interval to a log
+type Aggregator struct {
+	out       Log
+	maxGroups int
+	groups    map[string]*ErrorGroup
+	changed   map[string]int // fingerprint - count in current interval
+	stop      chan struct{}
+	stopOnce  sync.Once
+	mutex     sync.Mutex
+}
+
+// NewAggregator return an aggregator keeping at most maxGroups groups,
+// the least recently seen group is dropped when full
+func NewAggregator(out Log, interval time.Duration, maxGroups int) *Aggregator {
+	a := &Aggregator{
+		out:       out,
+		maxGroups: maxGroups,
+		groups:    make(map[string]*ErrorGroup),
+		changed:   make(map[string]int),
+		stop:      make(chan struct{}),
+	}
+	go a.serve(interval)
+	return a
+}
+
+// Hook add e to its group, use it as a HookFunc of ERROR entries
+func (a *Aggregator) Hook(e *Entry) error {
+	caller := ""
+	if value, ok := e.Field(CallerKey); ok {
+		caller = fmt.Sprint(value)
+	}
+	a.Add(e.Message, caller, e.Time)
+	return nil
+}
+
+// Add a message logged by caller at t
+func (a *Aggregator) Add(message, caller string, t time.Time) {
+	pattern := Normalize(message)
+	fp := Fingerprint(pattern, caller)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	g, ok := a.groups[fp]
+	if !ok {
+		if a.maxGroups > 0 && len(a.groups) >= a.maxGroups {
+			a.evict()
+		}
+		g = &ErrorGroup{
+			Fingerprint: fp,
+			Pattern:     pattern,
+			Caller:      caller,
+			FirstSeen:   t,
+		}
+		a.groups[fp] = g
+	}
+	g.Count++
+	g.LastSeen = t
+	g.Sample = message
+	a.changed[fp]++
+}
+
+// evict drop the least recently seen group
+func (a *Aggregator) evict() {
+	var oldest *ErrorGroup
+	for _, g := range a.groups {
+		if oldest == nil || g.LastSeen.Before(oldest.LastSeen) {
+			oldest = g
+		}
+	}
+	if oldest != nil {
+		delete(a.groups, oldest.Fingerprint)
+		delete(a.changed, oldest.Fingerprint)
+	}
+}
+
+// Groups return every group, most frequent first
+func (a *Aggregator) Groups() []ErrorGroup {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	result := make([]ErrorGroup, 0, len(a.groups))
+	for _, g := range a.groups {
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Fingerprint < result[j].Fingerprint
+	})
+	return result
+}
+
+// Group return group of a fingerprint
+func (a *Aggregator) Group(fingerprint string) (ErrorGroup, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if g, ok := a.groups[fingerprint]; ok {
+		return *g, true
+	}
+	return ErrorGroup{}, false
+}
+
+// Summary write groups seen since the last summary
+func (a *Aggregator) Summary() {
+	a.mutex.Lock()
+	changed := a.changed
+	a.changed = make(map[string]int)
+	groups := make([]ErrorGroup, 0, len(changed))
+	for fp := range changed {
+		if g, ok := a.groups[fp]; ok {
+			groups = append(groups, *g)
+		}
+	}
+	a.mutex.Unlock()
+
+	sort.Slice(groups, func(i, j int) bool {
+		return changed[groups[i].Fingerprint] > changed[groups[j].Fingerprint]
+	})
+	for _, g := range groups {
+		a.out.WARN("error group",
+			Any("fingerprint", g.Fingerprint),
+			Any("new", changed[g.Fingerprint]),
+			Any("count", g.Count),
+			Any("group_caller", g.Caller), // caller fields are dropped unless LOG_CALLER=1
+			Any("first_seen", g.FirstSeen.Format(time.RFC3339)),
+			Any("last_seen", g.LastSeen.Format(time.RFC3339)),
+			Any("pattern", g.Pattern),
+			Any("sample", g.Sample),
+		)
+	}
+}
+
+// serve write summary every interval
+func (a *Aggregator) serve(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.Summary()
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// Stop linter
+func (a *Aggregator) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+}
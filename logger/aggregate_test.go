@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"1sMmzDbIyyNbFqHe0oWAa9MKrWs handler err: io timeout":         "<ksuid> handler err: io timeout",
+		"client-42 handler err: write udp 10.0.0.1:5000: broken pipe": "<id> handler err: write udp <n>:<n>: broken pipe",
+		"seat 3 packet 0x1f2e size 1200":                              "seat <n> packet <hex> size <n>",
+		"id 3f9a2c1b7e missing":                                       "id <hex> missing",
+		"550e8400-e29b-41d4-a716-446655440000 closed":                 "<uuid> closed",
+	}
+	for in, want := range cases {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	buf := &bytes.Buffer{}
+	a := NewAggregator(newTestLog(buf), 0, 2)
+	defer a.Stop()
+
+	now := time.Now()
+	a.Hook(&Entry{Time: now, Level: ErrorLevel, Message: "client-1 handler err: EOF", Fields: []Field{Any(CallerKey, "fwd.go:10")}})
+	a.Hook(&Entry{Time: now.Add(time.Second), Level: ErrorLevel, Message: "client-2 handler err: EOF", Fields: []Field{Any(CallerKey, "fwd.go:10")}})
+	a.Hook(&Entry{Time: now, Level: ErrorLevel, Message: "client-3 handler err: EOF", Fields: []Field{Any(CallerKey, "other.go:1")}})
+
+	groups := a.Groups()
+	if len(groups) != 2 {
+		t.Fatalf("expect 2 groups, got %+v", groups)
+	}
+	g := groups[0]
+	if g.Count != 2 || g.Caller != "fwd.go:10" || g.Pattern != "<id> handler err: EOF" ||
+		g.Sample != "client-2 handler err: EOF" || !g.FirstSeen.Equal(now) || !g.LastSeen.Equal(now.Add(time.Second)) {
+		t.Fatalf("unexpected group %+v", g)
+	}
+	if found, ok := a.Group(g.Fingerprint); !ok || found.Count != 2 {
+		t.Fatalf("group %s not found", g.Fingerprint)
+	}
+
+	a.Summary()
+	if strings.Count(buf.String(), "WARN error group") != 2 || !strings.Contains(buf.String(), "new=2 count=2 group_caller=fwd.go:10") {
+		t.Fatalf("unexpected summary %q", buf.String())
+	}
+	buf.Reset()
+	a.Summary()
+	if buf.Len() != 0 {
+		t.Fatalf("summary should only write new errors, got %q", buf.String())
+	}
+
+	// full, the least recently seen group is dropped
+	a.Add("new error", "x.go:1", now.Add(time.Minute))
+	if len(a.Groups()) != 2 {
+		t.Fatalf("expect 2 groups, got %+v", a.Groups())
+	}
+	if _, ok := a.Group(Fingerprint("<id> handler err: EOF", "other.go:1")); ok {
+		t.Fatal("oldest group should be evicted")
+	}
+}
+
+func TestCaller(t *testing.T) {
+	f := Caller(0)
+	if s, _ := f.Interface().(string); !strings.HasPrefix(s, "logger/aggregate_test.go:") {
+		t.Fatalf("unexpected caller %v", f)
+	}
+}
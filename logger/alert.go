@@ -0,0 +1,316 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AlertRule fire when more than Threshold entries match in Window
+type AlertRule struct {
+	Name      string
+	Level     Level         // entries of this level or above
+	GroupBy   string        // field key, counters are kept per value (e.g. stream_id)
+	Value     string        // only count this value of GroupBy if set
+	Stack     string        // count a STACK id instead of entries
+	Threshold int           // fire when count > Threshold
+	Window    time.Duration // sliding window, counted in slots of Window/60
+	Cooldown  time.Duration // min time between two firing of the same group
+}
+
+// match return group of e and true if e is counted by the rule
+func (r *AlertRule) match(e *Entry) (string, bool) {
+	if r.Stack != "" || e.Level < r.Level {
+		return "", false
+	}
+	if r.GroupBy == "" {
+		return "", true
+	}
+	value, ok := e.Field(r.GroupBy)
+	if !ok {
+		return "", false
+	}
+	group := fmt.Sprint(value)
+	if r.Value != "" && group != r.Value {
+		return "", false
+	}
+	return group, true
+}
+
+// AlertFormat is the webhook payload format
+type AlertFormat int
+
+const (
+	// AlertGeneric post an Alert as json
+	AlertGeneric AlertFormat = iota
+	// AlertSlack post a slack compatible {"text": "..."}
+	AlertSlack
+)
+
+// Alert is the generic webhook payload
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Status    string    `json:"status"` // firing or resolved
+	Group     string    `json:"group,omitempty"`
+	Count     int       `json:"count"`
+	Threshold int       `json:"threshold"`
+	Window    string    `json:"window"`
+	Sample    string    `json:"sample,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// text return a one line description of the alert
+func (a *Alert) text() string {
+	group := ""
+	if a.Group != "" {
+		group = " for " + a.Group
+	}
+	s := fmt.Sprintf("[%s] %s: %d entries%s in %s (threshold %d)",
+		a.Status, a.Rule, a.Count, group, a.Window, a.Threshold)
+	if a.Sample != "" {
+		s += "\nsample: " + a.Sample
+	}
+	return s
+}
+
+// alertSlots is the number of slots a rule window is counted in
+const alertSlots = 60
+
+// alertSlot count matches from start to start + window/alertSlots
+type alertSlot struct {
+	start time.Time
+	n     int
+}
+
+// alertState is the counter of a rule and group
+type alertState struct {
+	rule   *AlertRule
+	group  string
+	slots  []alertSlot // oldest first, at most alertSlots+1 in window
+	sample string      // last matching message
+	firing bool
+	fired  time.Time // last firing
+}
+
+// count return matches in window at now
+func (s *alertState) count(now time.Time) int {
+	n := 0
+	for _, slot := range s.slots {
+		if now.Sub(slot.start) <= s.rule.Window {
+			n += slot.n
+		}
+	}
+	return n
+}
+
+func (s *alertState) add(now time.Time, sample string) {
+	expired := 0
+	for expired < len(s.slots) && now.Sub(s.slots[expired].start) > s.rule.Window {
+		expired++
+	}
+	s.slots = s.slots[expired:]
+
+	if last := len(s.slots) - 1; last >= 0 && !now.Before(s.slots[last].start) &&
+		now.Sub(s.slots[last].start) < s.rule.Window/alertSlots {
+		s.slots[last].n++
+	} else {
+		s.slots = append(s.slots, alertSlot{start: now, n: 1})
+	}
+	s.sample = sample
+}
+
+// Alerter count entries and STACK ids against rules and post alerts to a webhook.
+// A firing group is not sent again until it is resolved, and does not fire
+// again before the rule cooldown.
+type Alerter struct {
+	url      string
+	format   AlertFormat
+	client   *http.Client
+	rules    []*AlertRule
+	states   map[string]*alertState // rule name/group - state
+	queue    chan *Alert
+	errors   chan error
+	stop     chan struct{}
+	stopOnce sync.Once
+	mutex    sync.Mutex
+}
+
+// NewAlerter return an alerter posting to url, resolved alerts are checked every interval
+func NewAlerter(url string, format AlertFormat, interval time.Duration, rules ...AlertRule) *Alerter {
+	a := &Alerter{
+		url:    url,
+		format: format,
+		client: &http.Client{Timeout: 10 * time.Second},
+		states: make(map[string]*alertState),
+		queue:  make(chan *Alert, 100),
+		errors: make(chan error, 100),
+		stop:   make(chan struct{}),
+	}
+	for i := range rules {
+		rule := rules[i]
+		a.rules = append(a.rules, &rule)
+	}
+	go a.send()
+	go a.serve(interval)
+	return a
+}
+
+// Errors return webhook errors, they are dropped when nobody reads
+func (a *Alerter) Errors() <-chan error {
+	return a.errors
+}
+
+// Hook count e, use it as a HookFunc
+func (a *Alerter) Hook(e *Entry) error {
+	for _, rule := range a.rules {
+		if group, ok := rule.match(e); ok {
+			a.observe(rule, group, e.Time, e.Text())
+		}
+	}
+	return nil
+}
+
+// STACK count stack ids
+func (a *Alerter) STACK(ids ...string) {
+	now := time.Now()
+	for _, id := range ids {
+		for _, rule := range a.rules {
+			if rule.Stack == id {
+				a.observe(rule, id, now, "")
+			}
+		}
+	}
+}
+
+func (a *Alerter) observe(rule *AlertRule, group string, now time.Time, sample string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	key := rule.Name + "/" + group
+	s, ok := a.states[key]
+	if !ok {
+		s = &alertState{rule: rule, group: group}
+		a.states[key] = s
+	}
+	s.add(now, sample)
+	a.evaluate(s, now)
+}
+
+// Evaluate check every counter at now, fire or resolve alerts
+func (a *Alerter) Evaluate(now time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for key, s := range a.states {
+		a.evaluate(s, now)
+		if !s.firing && s.count(now) == 0 && now.Sub(s.fired) > s.rule.Cooldown {
+			delete(a.states, key)
+		}
+	}
+}
+
+func (a *Alerter) evaluate(s *alertState, now time.Time) {
+	count := s.count(now)
+	switch {
+	case !s.firing && count > s.rule.Threshold:
+		if !s.fired.IsZero() && now.Sub(s.fired) < s.rule.Cooldown {
+			return
+		}
+		s.firing = true
+		s.fired = now
+		a.enqueue(s, "firing", count, now)
+	case s.firing && count <= s.rule.Threshold:
+		s.firing = false
+		a.enqueue(s, "resolved", count, now)
+	}
+}
+
+func (a *Alerter) enqueue(s *alertState, status string, count int, now time.Time) {
+	alert := &Alert{
+		Rule:      s.rule.Name,
+		Status:    status,
+		Group:     s.group,
+		Count:     count,
+		Threshold: s.rule.Threshold,
+		Window:    s.rule.Window.String(),
+		Sample:    s.sample,
+		Time:      now,
+	}
+	select {
+	case a.queue <- alert:
+	default:
+		a.report(fmt.Errorf("alert queue is full, drop %s %s", status, s.rule.Name))
+	}
+}
+
+// payload encode alert with the webhook format
+func (a *Alerter) payload(alert *Alert) ([]byte, error) {
+	if a.format == AlertSlack {
+		return json.Marshal(map[string]string{"text": alert.text()})
+	}
+	return json.Marshal(alert)
+}
+
+// post send one alert to the webhook
+func (a *Alerter) post(alert *Alert) error {
+	body, err := a.payload(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Post(a.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("alert webhook return %s", resp.Status)
+	}
+	return nil
+}
+
+func (a *Alerter) report(err error) {
+	select {
+	case a.errors <- err:
+	default:
+	}
+}
+
+// send post queued alerts
+func (a *Alerter) send() {
+	for {
+		select {
+		case alert := <-a.queue:
+			if err := a.post(alert); err != nil {
+				a.report(err)
+			}
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// serve evaluate every interval to resolve alerts
+func (a *Alerter) serve(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			a.Evaluate(now)
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// Stop linter
+func (a *Alerter) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.stop)
+	})
+}
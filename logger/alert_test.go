@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// webhook return a local server sending received bodies to a channel
+func webhook(t *testing.T) (*httptest.Server, chan []byte) {
+	bodies := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		bodies <- body
+	}))
+	return server, bodies
+}
+
+func receive(t *testing.T, bodies chan []byte) []byte {
+	t.Helper()
+	select {
+	case body := <-bodies:
+		return body
+	case <-time.After(2 * time.Second):
+		t.Fatal("no alert received")
+	}
+	return nil
+}
+
+func noReceive(t *testing.T, bodies chan []byte) {
+	t.Helper()
+	select {
+	case body := <-bodies:
+		t.Fatalf("unexpected alert %s", body)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAlerterFireAndResolve(t *testing.T) {
+	server, bodies := webhook(t)
+	defer server.Close()
+
+	a := NewAlerter(server.URL, AlertGeneric, 0, AlertRule{
+		Name:      "stream errors",
+		Level:     ErrorLevel,
+		GroupBy:   "stream_id",
+		Threshold: 3,
+		Window:    time.Minute,
+		Cooldown:  time.Hour,
+	})
+	defer a.Stop()
+
+	for i := 0; i < 3; i++ {
+		a.Hook(NewEntry(ErrorLevel, "handler err", Any("stream_id", "X")))
+		a.Hook(NewEntry(WarnLevel, "not counted", Any("stream_id", "X")))
+		a.Hook(NewEntry(ErrorLevel, "handler err", Any("stream_id", "Y")))
+	}
+	noReceive(t, bodies)
+
+	a.Hook(NewEntry(ErrorLevel, "handler err", Any("stream_id", "X")))
+	var alert Alert
+	if err := json.Unmarshal(receive(t, bodies), &alert); err != nil {
+		t.Fatal(err)
+	}
+	if alert.Status != "firing" || alert.Group != "X" || alert.Count != 4 || alert.Sample != "handler err stream_id=X" {
+		t.Fatalf("unexpected alert %+v", alert)
+	}
+
+	// deduplicated while firing
+	a.Hook(NewEntry(ErrorLevel, "handler err", Any("stream_id", "X")))
+	noReceive(t, bodies)
+
+	a.Evaluate(time.Now().Add(2 * time.Minute))
+	if err := json.Unmarshal(receive(t, bodies), &alert); err != nil {
+		t.Fatal(err)
+	}
+	if alert.Status != "resolved" || alert.Group != "X" {
+		t.Fatalf("unexpected alert %+v", alert)
+	}
+
+	// cooldown
+	for i := 0; i < 5; i++ {
+		a.Hook(NewEntry(ErrorLevel, "handler err", Any("stream_id", "X")))
+	}
+	noReceive(t, bodies)
+}
+
+func TestAlerterSlackStack(t *testing.T) {
+	server, bodies := webhook(t)
+	defer server.Close()
+
+	a := NewAlerter(server.URL, AlertSlack, 0, AlertRule{
+		Name:      "reconnects",
+		Stack:     "reconnect",
+		Threshold: 1,
+		Window:    time.Minute,
+	})
+	defer a.Stop()
+
+	a.STACK("reconnect", "other")
+	noReceive(t, bodies)
+	a.STACK("reconnect")
+
+	var payload map[string]string
+	if err := json.Unmarshal(receive(t, bodies), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(payload["text"], "[firing] reconnects: 2 entries for reconnect in 1m0s") {
+		t.Fatalf("unexpected payload %v", payload)
+	}
+}
+
+func TestAlerterWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	a := NewAlerter(server.URL, AlertGeneric, 0, AlertRule{Name: "any", Threshold: 0, Window: time.Minute})
+	defer a.Stop()
+	a.Hook(NewEntry(InfoLevel, "x"))
+
+	select {
+	case err := <-a.Errors():
+		if !strings.Contains(err.Error(), "500") {
+			t.Fatalf("unexpected error %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expect webhook error")
+	}
+}
+
+func TestAlertStateCount(t *testing.T) {
+	s := &alertState{rule: &AlertRule{Threshold: 3, Window: time.Minute}}
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		s.add(start.Add(time.Duration(i)*100*time.Millisecond), "err")
+	}
+	if n := s.count(start.Add(10 * time.Second)); n != 100 {
+		t.Fatalf("expect 100 matches in window, got %d", n)
+	}
+	if len(s.slots) > alertSlots+1 {
+		t.Fatalf("expect at most %d slots, got %d", alertSlots+1, len(s.slots))
+	}
+	if n := s.count(start.Add(2 * time.Minute)); n != 0 {
+		t.Fatalf("expect 0 matches after window, got %d", n)
+	}
+}
@@ -0,0 +1,252 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// AuditLog write a tamper evident chain of json records, one per line:
+//
+//	{"time":"...","event":"login","user":"bob","seq":2,"prev":"<hash of seq 1>","hash":"...","mac":"..."}
+//
+// hash is the sha256 of the line up to prev, closed by '}', so it covers
+// the previous hash. mac is the hmac-sha256 of hash when a key is set.
+// Use VerifyAudit or an AuditVerifier to find the first broken link.
+type AuditLog struct {
+	out   io.Writer
+	key   []byte
+	clock Clock
+	seq   uint64
+	prev  string // hash of the last record
+	mutex sync.Mutex
+}
+
+// NewAuditLog return an audit log starting a new chain in out, key can be nil
+// Only the WithClock option is used
+func NewAuditLog(out io.Writer, key []byte, opts ...Option) *AuditLog {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &AuditLog{out: out, key: key, clock: o.clock}
+}
+
+// OpenAuditFile return an audit log appending to a rotating file.
+// The chain continues from the last record of cfg.Path or of its newest rotated file.
+func OpenAuditFile(cfg FileConfig, key []byte) (*AuditLog, error) {
+	last, err := lastAuditRecord(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := NewRotatingFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	a := NewAuditLog(file, key, WithClock(file.cfg.Clock))
+	a.seq, a.prev = last.Seq, last.Hash
+	return a, nil
+}
+
+// Record write an event with fields, the record is lost only if an error is returned
+func (a *AuditLog) Record(event string, fields ...Field) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	buf := append([]byte(nil), `{"time":"`...)
+	buf = TimeFormat{UTC: true}.appendTime(buf, a.clock.Now())
+	buf = append(buf, `","event":`...)
+	buf = appendJSONString(buf, event)
+	for _, field := range fields {
+		buf = append(buf, ',')
+		buf = appendJSONString(buf, field.Key)
+		buf = append(buf, ':')
+		buf = appendJSONValue(buf, field)
+	}
+	buf = append(buf, `,"seq":`...)
+	buf = strconv.AppendUint(buf, a.seq+1, 10)
+	buf = append(buf, `,"prev":`...)
+	buf = appendJSONString(buf, a.prev)
+
+	hash := auditHash(append(buf, '}'))
+	buf = append(buf, `,"hash":"`...)
+	buf = append(buf, hash...)
+	buf = append(buf, '"')
+	if a.key != nil {
+		buf = append(buf, `,"mac":"`...)
+		buf = append(buf, auditMAC(a.key, hash)...)
+		buf = append(buf, '"')
+	}
+	buf = append(buf, "}\n"...)
+
+	if _, err := a.out.Write(buf); err != nil {
+		return err
+	}
+	a.seq++
+	a.prev = hash
+	return nil
+}
+
+// Close the output if it is a closer
+func (a *AuditLog) Close() error {
+	if c, ok := a.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func auditHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func auditMAC(key []byte, hash string) string {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(hash))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// auditRecord is the chain part of a record
+type auditRecord struct {
+	Seq  uint64 `json:"seq"`
+	Prev string `json:"prev"`
+	Hash string `json:"hash"`
+	MAC  string `json:"mac"`
+}
+
+// AuditError is a broken link of an audit chain
+type AuditError struct {
+	Line   int    // line number in the verified reader
+	Seq    uint64 // expected sequence number
+	Reason string
+}
+
+func (e *AuditError) Error() string {
+	return fmt.Sprintf("audit chain broken at line %d (seq %d): %s", e.Line, e.Seq, e.Reason)
+}
+
+// AuditVerifier check audit chains, the chain continues between calls of
+// Verify so rotated files can be checked oldest first
+type AuditVerifier struct {
+	key  []byte
+	seq  uint64
+	prev string
+}
+
+// NewAuditVerifier return a verifier of a chain starting at seq 1,
+// macs are checked only if key is set
+func NewAuditVerifier(key []byte) *AuditVerifier {
+	return &AuditVerifier{key: key}
+}
+
+// Records return number of records verified
+func (v *AuditVerifier) Records() uint64 {
+	return v.seq
+}
+
+// Verify read records of r, return an *AuditError of the first broken link
+// or the read error
+func (v *AuditVerifier) Verify(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if err := v.verifyLine(scanner.Bytes()); err != "" {
+			return &AuditError{Line: n, Seq: v.seq + 1, Reason: err}
+		}
+	}
+	return scanner.Err()
+}
+
+// verifyLine check one record and move the chain, return the reason of a break
+func (v *AuditVerifier) verifyLine(line []byte) string {
+	var rec auditRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return "invalid record: " + err.Error()
+	}
+	i := bytes.LastIndex(line, []byte(`,"hash":"`))
+	if i < 0 {
+		return "missing hash"
+	}
+	body := append(line[:i:i], '}')
+
+	switch {
+	case rec.Seq != v.seq+1:
+		return fmt.Sprintf("unexpected seq %d", rec.Seq)
+	case rec.Prev != v.prev:
+		return "previous hash mismatch"
+	case auditHash(body) != rec.Hash:
+		return "hash mismatch"
+	case v.key != nil && rec.MAC == "":
+		return "missing mac"
+	case v.key != nil && !hmac.Equal([]byte(auditMAC(v.key, rec.Hash)), []byte(rec.MAC)):
+		return "mac mismatch"
+	}
+	v.seq = rec.Seq
+	v.prev = rec.Hash
+	return ""
+}
+
+// VerifyAudit check a whole audit chain of r, see AuditVerifier.Verify
+func VerifyAudit(r io.Reader, key []byte) error {
+	return NewAuditVerifier(key).Verify(r)
+}
+
+// VerifyAuditFiles check the chain of path and its rotated files, oldest first,
+// gzip rotated files are read too
+func VerifyAuditFiles(path string, key []byte) error {
+	v := NewAuditVerifier(key)
+	for _, name := range RotatedFiles(path) {
+		if err := verifyAuditFile(v, name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func verifyAuditFile(v *AuditVerifier, name string) error {
+	r, err := OpenLogFile(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return v.Verify(r)
+}
+
+// lastAuditRecord return the last record of path or of its newest rotated file
+func lastAuditRecord(path string) (auditRecord, error) {
+	files := RotatedFiles(path)
+	for i := len(files) - 1; i >= 0; i-- {
+		r, err := OpenLogFile(files[i])
+		if err != nil {
+			return auditRecord{}, err
+		}
+		var last []byte
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) > 0 {
+				last = append(last[:0], scanner.Bytes()...)
+			}
+		}
+		err = scanner.Err()
+		r.Close()
+		if err != nil {
+			return auditRecord{}, err
+		}
+		if last != nil {
+			var rec auditRecord
+			if err := json.Unmarshal(last, &rec); err != nil {
+				return auditRecord{}, fmt.Errorf("%s: last record: %w", files[i], err)
+			}
+			return rec, nil
+		}
+	}
+	return auditRecord{}, nil
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeAudit(t *testing.T, key []byte) []string {
+	buf := &bytes.Buffer{}
+	a := NewAuditLog(buf, key, WithClock(NewManualClock(time.Unix(100, 0))))
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if err := a.Record("login", String("user", user), Int("attempt", 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func auditBreak(t *testing.T, lines []string, key []byte) *AuditError {
+	err := VerifyAudit(strings.NewReader(strings.Join(lines, "")), key)
+	if err == nil {
+		return nil
+	}
+	var ae *AuditError
+	if !errors.As(err, &ae) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return ae
+}
+
+func TestAuditChain(t *testing.T) {
+	key := []byte("secret")
+	lines := writeAudit(t, key)
+	if len(lines) != 3 {
+		t.Fatalf("lines = %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], `{"time":"1970-01-01T00:01:40Z","event":"login","user":"alice","attempt":1,"seq":1,"prev":"","hash":"`) {
+		t.Errorf("record = %s", lines[0])
+	}
+	if err := auditBreak(t, lines, key); err != nil {
+		t.Fatalf("valid chain: %v", err)
+	}
+	if err := auditBreak(t, lines, nil); err != nil {
+		t.Fatalf("valid chain without key: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		lines  []string
+		key    []byte
+		line   int
+		reason string
+	}{
+		{"edited", []string{lines[0], strings.Replace(lines[1], "bob", "eve", 1), lines[2]}, nil, 2, "hash mismatch"},
+		{"removed", []string{lines[0], lines[2]}, key, 2, "unexpected seq 3"},
+		{"reordered", []string{lines[1], lines[0]}, key, 1, "unexpected seq 2"},
+		{"wrong key", lines, []byte("other"), 1, "mac mismatch"},
+		{"garbage", []string{lines[0], "oops\n"}, key, 2, "invalid record"},
+	}
+	for _, c := range cases {
+		err := auditBreak(t, c.lines, c.key)
+		if err == nil || err.Line != c.line || !strings.HasPrefix(err.Reason, c.reason) {
+			t.Errorf("%s: got %v, want line %d %s", c.name, err, c.line, c.reason)
+		}
+	}
+
+	// a record rewritten with a valid hash still break the next link
+	second := strings.Replace(lines[1], "bob", "eve", 1)
+	second = second[:strings.Index(second, `,"hash":"`)] + "}"
+	second = second[:len(second)-1] + `,"hash":"` + auditHash([]byte(second)) + `"}` + "\n"
+	if err := auditBreak(t, []string{lines[0], second, lines[2]}, nil); err == nil || err.Line != 3 || err.Reason != "previous hash mismatch" {
+		t.Errorf("forged: got %v", err)
+	}
+}
+
+func TestAuditFileResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := NewManualClock(time.Unix(100, 0))
+	cfg := FileConfig{Path: filepath.Join(dir, "audit.log"), Compress: true, Clock: clock}
+	key := []byte("secret")
+
+	a, err := OpenAuditFile(cfg, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Record("start")
+	a.Record("stop")
+	a.out.(*RotatingFile).Rotate()
+	a.Close()
+
+	// the chain continue from the rotated file
+	clock.Add(time.Second)
+	a, err = OpenAuditFile(cfg, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Record("start")
+	a.Close()
+
+	if files := RotatedFiles(cfg.Path); len(files) != 2 || !strings.HasSuffix(files[0], ".gz") {
+		t.Errorf("files = %v", files)
+	}
+	if err := VerifyAuditFiles(cfg.Path, key); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+}
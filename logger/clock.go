@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock give time to logs, tickers and rotation so tests can control it
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is a time.Ticker of a Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Clocked is implemented by logs stamping entries with a clock
+type Clocked interface {
+	Clock() Clock
+}
+
+// ClockOf return the clock of l, SystemClock if it has none
+func ClockOf(l Log) Clock {
+	if c, ok := l.(Clocked); ok {
+		return c.Clock()
+	}
+	return SystemClock
+}
+
+// SystemClock is the real clock
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return &systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (s *systemTicker) C() <-chan time.Time {
+	return s.t.C
+}
+
+func (s *systemTicker) Stop() {
+	s.t.Stop()
+}
+
+// ManualClock only move with Add, tickers fire when their time is reached
+type ManualClock struct {
+	now     time.Time
+	tickers []*manualTicker
+	mutex   sync.Mutex
+}
+
+// NewManualClock return a clock stopped at now
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now linter
+func (m *ManualClock) Now() time.Time {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.now
+}
+
+// Set move the clock to t
+func (m *ManualClock) Set(t time.Time) {
+	m.mutex.Lock()
+	m.now = t
+	tickers := append([]*manualTicker(nil), m.tickers...)
+	m.mutex.Unlock()
+
+	for _, ticker := range tickers {
+		ticker.fire(t)
+	}
+}
+
+// Add move the clock forward by d
+func (m *ManualClock) Add(d time.Duration) {
+	m.Set(m.Now().Add(d))
+}
+
+// NewTicker linter
+func (m *ManualClock) NewTicker(d time.Duration) Ticker {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	t := &manualTicker{
+		clock:  m,
+		period: d,
+		next:   m.now.Add(d),
+		c:      make(chan time.Time, 1),
+	}
+	m.tickers = append(m.tickers, t)
+	return t
+}
+
+func (m *ManualClock) remove(t *manualTicker) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for i, item := range m.tickers {
+		if item == t {
+			m.tickers = append(m.tickers[:i:i], m.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+type manualTicker struct {
+	clock  *ManualClock
+	period time.Duration
+	next   time.Time
+	c      chan time.Time
+	mutex  sync.Mutex
+}
+
+func (t *manualTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTicker) Stop() {
+	t.clock.remove(t)
+}
+
+// fire send one tick if now reached next tick, like time.Ticker slow receivers miss ticks
+func (t *manualTicker) fire(now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if now.Before(t.next) {
+		return
+	}
+	for !now.Before(t.next) {
+		t.next = t.next.Add(t.period)
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2020, 9, 10, 8, 30, 15, 123456789, time.FixedZone("ICT", 7*3600))
+
+func TestManualClockTicker(t *testing.T) {
+	clock := NewManualClock(epoch)
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	clock.Add(500 * time.Millisecond)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired early")
+	default:
+	}
+
+	clock.Add(600 * time.Millisecond)
+	select {
+	case now := <-ticker.C():
+		if !now.Equal(epoch.Add(1100 * time.Millisecond)) {
+			t.Fatalf("unexpected tick %v", now)
+		}
+	default:
+		t.Fatal("ticker should fire")
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	e := &Entry{Time: epoch, Level: InfoLevel, Message: "hi"}
+	cases := []struct {
+		enc  Encoder
+		want string
+	}{
+		{&TextEncoder{}, "2020-09-10T08:30:15.123456789+07:00 INFO hi\n"},
+		{&TextEncoder{Time: TimeFormat{UTC: true}}, "2020-09-10T01:30:15.123456789Z INFO hi\n"},
+		{&JSONEncoder{Time: TimeFormat{Layout: TimeUnixMilli}}, `{"time":1599701415123,"level":"INFO","msg":"hi"}` + "\n"},
+		{&JSONEncoder{Time: TimeFormat{Layout: time.RFC3339, UTC: true}}, `{"time":"2020-09-10T01:30:15Z","level":"INFO","msg":"hi"}` + "\n"},
+		{&TextEncoder{Time: TimeFormat{Layout: TimeUnix}}, "1599701415 INFO hi\n"},
+	}
+	for _, c := range cases {
+		b, _ := c.enc.Encode(e)
+		if string(b) != c.want {
+			t.Errorf("got %q, want %q", b, c.want)
+		}
+	}
+}
+
+func TestFactorLogClock(t *testing.T) {
+	buf := &bytes.Buffer{}
+	clock := NewManualClock(epoch)
+	l := NewFactorLog(WithOutput(buf), WithClock(clock), WithUTC())
+	l.INFO("stamped")
+	want := "[2020-09-10] [01:30:15.123456789] [INFO] [stamped]\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gologs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := NewManualClock(time.Date(2020, 9, 10, 23, 59, 0, 0, time.UTC))
+	path := filepath.Join(dir, "app.log")
+	f, err := NewRotatingFile(FileConfig{Path: path, MaxSize: 10, Interval: 24 * time.Hour, Compress: true, Clock: clock})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Write([]byte("12345678\n"))
+	f.Write([]byte("size\n")) // over max size
+	clock.Add(2 * time.Minute)
+	f.Write([]byte("new day\n"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	first := path + ".20200910T235900.000.gz"
+	second := path + ".20200911T000100.000.gz"
+	for name, want := range map[string]string{first: "12345678\n", second: "size\n"} {
+		file, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(zr)
+		file.Close()
+		if string(b) != want {
+			t.Fatalf("%s: got %q, want %q", name, b, want)
+		}
+	}
+	b, _ := ioutil.ReadFile(path)
+	if string(b) != "new day\n" {
+		t.Fatalf("unexpected current file %q", b)
+	}
+	if matches, _ := filepath.Glob(path + ".*"); len(matches) != 2 || strings.HasSuffix(matches[0], "000") {
+		t.Fatalf("unexpected rotated files %v", matches)
+	}
+}
+
+func TestRotatingFileSameTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gologs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	f, err := NewRotatingFile(FileConfig{Path: path, Clock: NewManualClock(time.Date(2020, 9, 10, 0, 0, 0, 0, time.UTC))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, line := range []string{"a\n", "b\n", "c\n"} {
+		f.Write([]byte(line))
+		if err := f.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := RotatedFiles(path)
+	want := []string{path + ".20200910T000000.000", path + ".20200910T000000.000-1", path + ".20200910T000000.000-2", path}
+	if strings.Join(files, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v, want %v", files, want)
+	}
+
+	// the file is reopened when the rename fails
+	os.Remove(path)
+	if err := f.Rotate(); err == nil {
+		t.Fatal("expected the rename error")
+	}
+	if _, err := f.Write([]byte("d\n")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "d\n" {
+		t.Fatalf("unexpected current file %q", b)
+	}
+
+	// a write rotating the file is kept and the error reported
+	var reported []error
+	g, err := NewRotatingFile(FileConfig{Path: path, MaxSize: 3, OnError: func(err error) { reported = append(reported, err) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	os.Remove(path)
+	if _, err := g.Write([]byte("e\n")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != "e\n" || len(reported) != 1 {
+		t.Fatalf("current file %q, reported %v", b, reported)
+	}
+}
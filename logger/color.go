@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ColorMode decide when a log write ansi color
+type ColorMode int
+
+const (
+	// ColorAuto color only when output is a terminal
+	ColorAuto ColorMode = iota
+	// ColorAlways always write color
+	ColorAlways
+	// ColorNever never write color
+	ColorNever
+)
+
+// ColorScheme map a severity (FATAL, PANIC, ERROR, WARN, INFO, DEBUG, STACK) to a color name
+// Color names follow https://github.com/mgutz/ansi (e.g. "red", "cyan+b")
+type ColorScheme map[string]string
+
+// severities order to render color scheme
+var severities = []string{"FATAL", "PANIC", "ERROR", "WARN", "INFO", "DEBUG", "STACK"}
+
+// DefaultColorScheme linter
+func DefaultColorScheme() ColorScheme {
+	return ColorScheme{
+		"FATAL": "red+b",
+		"PANIC": "red+b",
+		"ERROR": "red",
+		"WARN":  "yellow",
+		"INFO":  "green",
+		"DEBUG": "cyan",
+		"STACK": "blue",
+	}
+}
+
+// format return factorlog color verbs for this scheme
+func (c ColorScheme) format() string {
+	var b strings.Builder
+	for _, sev := range severities {
+		if color, ok := c[sev]; ok && color != "" {
+			fmt.Fprintf(&b, `%%{Color "%s" "%s"}`, color, sev)
+		}
+	}
+	return b.String()
+}
+
+// useColor resolve color mode with env and output
+// NO_COLOR (not empty) disable color, FORCE_COLOR (not "0") force color
+func useColor(mode ColorMode, out io.Writer) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" && force != "0" {
+		return true
+	}
+	return isTerminal(out)
+}
+
+// isTerminal check out is a tty
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(*os.File)
+	if !ok {
+		return false
+	}
+	fd := f.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
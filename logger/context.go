@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	correlationKey contextKey = iota
+	traceKey
+)
+
+// CorrelationKey is the field key of correlation id
+const CorrelationKey = "correlation_id"
+
+// WithCorrelationID return a context carrying a correlation id of a request or stream
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey, id)
+}
+
+// CorrelationID return correlation id carried by ctx, empty if none
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationKey).(string)
+	return id
+}
+
+const (
+	// TraceKey is the field key of trace id, 32 hex digits
+	TraceKey = "trace_id"
+	// SpanKey is the field key of span id, 16 hex digits
+	SpanKey = "span_id"
+)
+
+type traceContext struct {
+	traceID, spanID string
+}
+
+// WithTrace return a context carrying the trace and span ids of the current span
+func WithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceKey, traceContext{traceID, spanID})
+}
+
+// WithTraceParent return a context carrying ids of a W3C traceparent header
+//
+//	00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func WithTraceParent(ctx context.Context, header string) (context.Context, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || !isHexID(parts[1], 16) || !isHexID(parts[2], 8) {
+		return ctx, fmt.Errorf("invalid traceparent %q", header)
+	}
+	return WithTrace(ctx, parts[1], parts[2]), nil
+}
+
+// isHexID return true if s is n bytes in lower case hex and not all zero
+func isHexID(s string, n int) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == n && s == strings.ToLower(s) && strings.Trim(s, "0") != ""
+}
+
+// Trace return trace and span ids carried by ctx, empty if none
+func Trace(ctx context.Context) (traceID, spanID string) {
+	if ctx == nil {
+		return "", ""
+	}
+	t, _ := ctx.Value(traceKey).(traceContext)
+	return t.traceID, t.spanID
+}
+
+// ContextFields return fields carried by ctx
+func ContextFields(ctx context.Context) []Field {
+	var fields []Field
+	if id := CorrelationID(ctx); id != "" {
+		fields = append(fields, String(CorrelationKey, id))
+	}
+	if traceID, spanID := Trace(ctx); traceID != "" {
+		fields = append(fields, String(TraceKey, traceID))
+		if spanID != "" {
+			fields = append(fields, String(SpanKey, spanID))
+		}
+	}
+	return fields
+}
+
+// ContextArgs append fields carried by ctx to values of a Log method
+func ContextArgs(ctx context.Context, v []interface{}) []interface{} {
+	fields := ContextFields(ctx)
+	if len(fields) == 0 {
+		return v
+	}
+	args := make([]interface{}, 0, len(v)+len(fields))
+	args = append(args, v...)
+	for _, field := range fields {
+		args = append(args, field)
+	}
+	return args
+}
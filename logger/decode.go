@@ -0,0 +1,341 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Decoder parse a line written by an encoder back into an entry
+type Decoder interface {
+	Decode(line []byte) (*Entry, error)
+}
+
+// ParseDecoder return the decoder of a format: auto, json, logfmt or text
+func ParseDecoder(format string) (Decoder, error) {
+	switch format {
+	case "", "auto":
+		return AutoDecoder{}, nil
+	case "json":
+		return JSONDecoder{}, nil
+	case "logfmt":
+		return LogfmtDecoder{}, nil
+	case "text":
+		return TextDecoder{}, nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+var errNotEntry = errors.New("line is not a log entry")
+
+// AutoDecoder detect the format of each line: json, logfmt or text.
+// A line of none of them is an INFO entry with the line as message and no time.
+type AutoDecoder struct{}
+
+// Decode linter
+func (AutoDecoder) Decode(line []byte) (*Entry, error) {
+	line = bytes.TrimRight(line, "\r\n")
+	var e *Entry
+	var err error
+	switch {
+	case len(line) > 0 && line[0] == '{':
+		e, err = JSONDecoder{}.Decode(line)
+	case bytes.HasPrefix(line, []byte("time=")) || bytes.HasPrefix(line, []byte("ts=")) || bytes.HasPrefix(line, []byte("level=")):
+		e, err = LogfmtDecoder{}.Decode(line)
+	default:
+		e, err = TextDecoder{}.Decode(line)
+	}
+	if err != nil {
+		return &Entry{Level: InfoLevel, Message: string(line)}, nil
+	}
+	return e, nil
+}
+
+// JSONDecoder read lines of the JSONEncoder, field order is kept
+type JSONDecoder struct{}
+
+// Decode linter
+func (JSONDecoder) Decode(line []byte) (*Entry, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errNotEntry
+	}
+
+	e := &Entry{Level: InfoLevel}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		if err := e.set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// set the time, level or message of a decoded key, else add a field
+func (e *Entry) set(key string, value interface{}) error {
+	var err error
+	switch key {
+	case "time", "ts":
+		e.Time, err = parseEntryTime(fmt.Sprint(value))
+	case "level", "lvl":
+		e.Level, err = ParseLevel(fmt.Sprint(value))
+	case "msg", "message":
+		e.Message = fmt.Sprint(value)
+	default:
+		e.Fields = append(e.Fields, decodedField(key, value))
+	}
+	return err
+}
+
+// decodedField return a typed field of a decoded value
+func decodedField(key string, value interface{}) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return Int64(key, n)
+		}
+		f, _ := v.Float64()
+		return Any(key, f)
+	}
+	return Any(key, value)
+}
+
+// parseEntryTime parse RFC3339 or an epoch in seconds, milliseconds or nanoseconds
+func parseEntryTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		switch {
+		case n < 1e11:
+			return time.Unix(n, 0), nil
+		case n < 1e14:
+			return time.Unix(0, n*int64(time.Millisecond)), nil
+		}
+		return time.Unix(0, n), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// LogfmtDecoder read lines of the LogfmtEncoder, values are strings
+type LogfmtDecoder struct{}
+
+// Decode linter
+func (LogfmtDecoder) Decode(line []byte) (*Entry, error) {
+	e := &Entry{Level: InfoLevel}
+	s := string(bytes.TrimRight(line, "\r\n"))
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return e, nil
+		}
+		end := strings.IndexAny(s, "= ")
+		if end == 0 {
+			return nil, errNotEntry
+		}
+		if end < 0 || s[end] == ' ' {
+			// key without value
+			if end < 0 {
+				end = len(s)
+			}
+			e.Fields = append(e.Fields, String(s[:end], ""))
+			s = s[end:]
+			continue
+		}
+		key := s[:end]
+		s = s[end+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			n := quotedLen(s)
+			if n < 0 {
+				return nil, errNotEntry
+			}
+			if err := json.Unmarshal([]byte(s[:n]), &value); err != nil {
+				return nil, err
+			}
+			s = s[n:]
+		} else {
+			n := strings.IndexByte(s, ' ')
+			if n < 0 {
+				n = len(s)
+			}
+			value, s = s[:n], s[n:]
+		}
+		if err := e.set(key, value); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// quotedLen return length of the quoted string s starts with, -1 if not terminated
+func quotedLen(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// TextDecoder read lines of the TextEncoder, of the factorlog DefaultFormat
+// and of the standard log format of logs.Logging (date, time and [LEVEL]).
+// Fields are the key=value words at the end of the line, values are strings.
+type TextDecoder struct{}
+
+// Decode linter
+func (TextDecoder) Decode(line []byte) (*Entry, error) {
+	s := string(bytes.TrimRight(line, "\r\n"))
+	if strings.HasPrefix(s, "[") {
+		return decodeFactorLog(s)
+	}
+	if len(s) > 10 && s[4] == '/' && s[7] == '/' {
+		return decodeStdLog(s)
+	}
+
+	parts := strings.SplitN(s, " ", 3)
+	if len(parts) < 2 {
+		return nil, errNotEntry
+	}
+	t, err := parseEntryTime(parts[0])
+	if err != nil {
+		return nil, errNotEntry
+	}
+	level, err := ParseLevel(parts[1])
+	if err != nil {
+		return nil, errNotEntry
+	}
+	e := &Entry{Time: t, Level: level}
+	if len(parts) == 3 {
+		e.Message, e.Fields = splitTextFields(parts[2])
+	}
+	return e, nil
+}
+
+// decodeFactorLog parse [date] [time] [SEVERITY] [message] in local time
+func decodeFactorLog(s string) (*Entry, error) {
+	var parts []string
+	for i := 0; i < 3; i++ {
+		end := strings.Index(s, "] ")
+		if !strings.HasPrefix(s, "[") || end < 0 {
+			return nil, errNotEntry
+		}
+		parts = append(parts, s[1:end])
+		s = s[end+2:]
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05.999999999", parts[0]+" "+parts[1], time.Local)
+	if err != nil {
+		return nil, errNotEntry
+	}
+	level, err := ParseLevel(parts[2])
+	if err != nil {
+		return nil, errNotEntry
+	}
+	e := &Entry{Time: t, Level: level}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	e.Message, e.Fields = splitTextFields(s)
+	return e, nil
+}
+
+// decodeStdLog parse 2006/01/02 15:04:05[.000000] [SEVERITY] message in local time,
+// [STACK] counters are an INFO entry "stack" like the ones of the sinks
+func decodeStdLog(s string) (*Entry, error) {
+	parts := strings.SplitN(s, " ", 4)
+	if len(parts) < 3 || !strings.HasPrefix(parts[2], "[") || !strings.HasSuffix(parts[2], "]") {
+		return nil, errNotEntry
+	}
+	t, err := time.ParseInLocation("2006/01/02 15:04:05", parts[0]+" "+parts[1], time.Local)
+	if err != nil {
+		return nil, errNotEntry
+	}
+	rest := ""
+	if len(parts) == 4 {
+		rest = parts[3]
+	}
+	severity := parts[2][1 : len(parts[2])-1]
+	level, err := ParseLevel(severity)
+	if severity == "STACK" {
+		level, err, rest = InfoLevel, nil, strings.TrimSpace("stack "+rest)
+	}
+	if err != nil {
+		return nil, errNotEntry
+	}
+	e := &Entry{Time: t, Level: level}
+	e.Message, e.Fields = splitTextFields(rest)
+	return e, nil
+}
+
+// splitTextFields split the trailing key=value words of s,
+// a quoted value may have spaces, escaped quotes and backslashes
+func splitTextFields(s string) (string, []Field) {
+	type word struct {
+		start int
+		field *Field
+	}
+	var words []word
+	for i := 0; i <= len(s); {
+		w := word{start: i}
+		end := i + strings.IndexByte(s[i:]+" ", ' ')
+		eq := strings.IndexByte(s[i:end], '=')
+		if eq > 0 && !strings.ContainsAny(s[i:i+eq], `"[]`) {
+			key, value := s[i:i+eq], s[i+eq+1:end]
+			if strings.HasPrefix(value, `"`) {
+				if n := quotedLen(s[i+eq+1:]); n > 0 && (i+eq+1+n == len(s) || s[i+eq+1+n] == ' ') {
+					end = i + eq + 1 + n
+					value = unquoteText(s[i+eq+1 : end])
+				}
+			}
+			field := String(key, value)
+			w.field = &field
+		}
+		words = append(words, w)
+		i = end + 1
+	}
+
+	i := len(words)
+	for i > 1 && words[i-1].field != nil {
+		i--
+	}
+	var fields []Field
+	for _, w := range words[i:] {
+		fields = append(fields, *w.field)
+	}
+	if i == len(words) {
+		return s, fields
+	}
+	return s[:words[i].start-1], fields
+}
+
+// unquoteText return the quoted text value q without quotes and backslashes
+func unquoteText(q string) string {
+	q = q[1 : len(q)-1]
+	if strings.IndexByte(q, '\\') < 0 {
+		return q
+	}
+	b := make([]byte, 0, len(q))
+	for i := 0; i < len(q); i++ {
+		if q[i] == '\\' && i+1 < len(q) && (q[i+1] == '"' || q[i+1] == '\\') {
+			i++
+		}
+		b = append(b, q[i])
+	}
+	return string(b)
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDecodeRoundTrip(t *testing.T) {
+	e := &Entry{
+		Time:    time.Unix(1599700000, 123000000),
+		Level:   WarnLevel,
+		Message: "stream closed",
+		Fields:  []Field{String("stream_id", "a-1"), Int("size", 1200), Duration("rtt", 1500*time.Millisecond)},
+	}
+	encoders := map[string]Encoder{
+		"json":   &JSONEncoder{},
+		"logfmt": &LogfmtEncoder{},
+		"text":   &TextEncoder{},
+	}
+	for name, enc := range encoders {
+		line, err := enc.Encode(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec, _ := ParseDecoder(name)
+		for _, dec := range []Decoder{dec, AutoDecoder{}} {
+			got, err := dec.Decode(line)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if !got.Time.Equal(e.Time) || got.Level != e.Level || got.Message != e.Message {
+				t.Errorf("%s: got %v %v %q", name, got.Time, got.Level, got.Message)
+			}
+			if v, _ := got.Field("size"); v == nil || fmt.Sprint(v) != "1200" {
+				t.Errorf("%s: size %v", name, v)
+			}
+			if v, _ := got.Field("stream_id"); v != "a-1" {
+				t.Errorf("%s: stream_id %v", name, v)
+			}
+		}
+	}
+}
+
+func TestTextFieldInjection(t *testing.T) {
+	e := &Entry{
+		Time:    time.Unix(1599700000, 0),
+		Level:   InfoLevel,
+		Message: "login",
+		Fields:  []Field{String("user", "x level=ERROR"), String("note", `say "hi" \o/`), String("bad key", "v")},
+	}
+	line, _ := (&TextEncoder{}).Encode(e)
+	// the default factorlog backend quotes values the same way
+	var factor bytes.Buffer
+	WriteEntry(NewFactorLog(WithOutput(&factor), WithColor(ColorNever)), e)
+
+	for _, line := range [][]byte{line, factor.Bytes()} {
+		got, err := TextDecoder{}.Decode(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Message != "login" || len(got.Fields) != 3 {
+			t.Fatalf("got %q %v from %q", got.Message, got.Fields, line)
+		}
+		if v, _ := got.Field("user"); v != "x level=ERROR" {
+			t.Errorf("user %q from %q", v, line)
+		}
+		if v, _ := got.Field("note"); v != `say "hi" \o/` {
+			t.Errorf("note %q from %q", v, line)
+		}
+		if v, _ := got.Field("bad_key"); v != "v" {
+			t.Errorf("bad_key %q from %q", v, line)
+		}
+	}
+}
+
+func TestDecodeFactorLog(t *testing.T) {
+	e, err := TextDecoder{}.Decode([]byte("[2020-09-10] [08:30:01.5] [ERROR] [Remove id abc client_id=c1]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2020, 9, 10, 8, 30, 1, 500000000, time.Local)
+	if !e.Time.Equal(want) || e.Level != ErrorLevel || e.Message != "Remove id abc" {
+		t.Errorf("got %v %v %q", e.Time, e.Level, e.Message)
+	}
+	if v, _ := e.Field("client_id"); v != "c1" {
+		t.Errorf("client_id %v", v)
+	}
+}
+
+func TestAutoDecoderRaw(t *testing.T) {
+	e, err := AutoDecoder{}.Decode([]byte("panic: runtime error"))
+	if err != nil || e.Level != InfoLevel || e.Message != "panic: runtime error" || !e.Time.IsZero() {
+		t.Errorf("got %+v %v", e, err)
+	}
+}
+
+func TestDecodeStdLog(t *testing.T) {
+	e, err := AutoDecoder{}.Decode([]byte("2020/09/10 08:30:01.500000 [WARN] stream closed stream_id=abc\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2020, 9, 10, 8, 30, 1, 500000000, time.Local)
+	if !e.Time.Equal(want) || e.Level != WarnLevel || e.Message != "stream closed" {
+		t.Errorf("got %v %v %q", e.Time, e.Level, e.Message)
+	}
+	if v, _ := e.Field("stream_id"); v != "abc" {
+		t.Errorf("stream_id %v", v)
+	}
+
+	e, err = TextDecoder{}.Decode([]byte("2020/09/10 08:30:01 [STACK] a=2 b=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Time.IsZero() || e.Level != InfoLevel || e.Message != "stack" || len(e.Fields) != 2 {
+		t.Errorf("stack got %v %v %q %v", e.Time, e.Level, e.Message, e.Fields)
+	}
+}
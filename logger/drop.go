@@ -0,0 +1,66 @@
+package logger
+
+// DropFields remove fields of some keys before entries reach next, like the
+// caller location used by hooks but not written
+type DropFields struct {
+	leveled
+	next Log
+	keys map[string]bool
+}
+
+// NewDropFields return a log removing fields of keys in front of next
+func NewDropFields(next Log, keys ...string) *DropFields {
+	d := &DropFields{
+		next: next,
+		keys: make(map[string]bool),
+	}
+	for _, key := range keys {
+		d.keys[key] = true
+	}
+	d.leveled = d.log
+	return d
+}
+
+// STACK linter
+func (d *DropFields) STACK(v ...string) {
+	d.next.STACK(v...)
+}
+
+// WriteEntry write a copy of e without the dropped fields
+func (d *DropFields) WriteEntry(e *Entry) error {
+	return WriteEntry(d.next, d.drop(e))
+}
+
+// LogEntry log a copy of e without the dropped fields
+func (d *DropFields) LogEntry(e *Entry) {
+	LogEntry(d.next, d.drop(e))
+}
+
+func (d *DropFields) log(level Level, v ...interface{}) {
+	kept := v[:0:0]
+	for _, arg := range v {
+		if field, ok := arg.(Field); ok && d.keys[field.Key] {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	Emit(d.next, level, kept...)
+}
+
+// drop return e or a copy without the dropped fields
+func (d *DropFields) drop(e *Entry) *Entry {
+	for i, field := range e.Fields {
+		if !d.keys[field.Key] {
+			continue
+		}
+		clone := *e
+		clone.Fields = append([]Field(nil), e.Fields[:i]...)
+		for _, field := range e.Fields[i+1:] {
+			if !d.keys[field.Key] {
+				clone.Fields = append(clone.Fields, field)
+			}
+		}
+		return &clone
+	}
+	return e
+}
@@ -0,0 +1,295 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Encoder turn an entry into one line of bytes
+type Encoder interface {
+	Encode(e *Entry) ([]byte, error)
+}
+
+const (
+	// TimeUnix write time as seconds since epoch
+	TimeUnix = "unix"
+	// TimeUnixMilli write time as milliseconds since epoch
+	TimeUnixMilli = "unix_ms"
+	// TimeUnixNano write time as nanoseconds since epoch
+	TimeUnixNano = "unix_ns"
+)
+
+// TimeFormat is how encoders write entry time
+type TimeFormat struct {
+	Layout string // time layout or TimeUnix, TimeUnixMilli, TimeUnixNano, default time.RFC3339Nano
+	UTC    bool   // convert to UTC before formatting a layout
+}
+
+// epoch return time as a number and true if format is an epoch
+func (f TimeFormat) epoch(t time.Time) (int64, bool) {
+	switch f.Layout {
+	case TimeUnix:
+		return t.Unix(), true
+	case TimeUnixMilli:
+		return t.UnixNano() / int64(time.Millisecond), true
+	case TimeUnixNano:
+		return t.UnixNano(), true
+	}
+	return 0, false
+}
+
+// Format return t as text
+func (f TimeFormat) Format(t time.Time) string {
+	return string(f.appendTime(nil, t))
+}
+
+// appendTime append t as text
+func (f TimeFormat) appendTime(buf []byte, t time.Time) []byte {
+	if n, ok := f.epoch(t); ok {
+		return strconv.AppendInt(buf, n, 10)
+	}
+	if f.UTC {
+		t = t.UTC()
+	}
+	layout := f.Layout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	return t.AppendFormat(buf, layout)
+}
+
+// TextEncoder write entries as
+//
+//	2006-01-02T15:04:05.000000000Z07:00 INFO message key=value
+//
+// Message, keys and values are escaped with AppendEscaped so an entry is one line,
+// Multiline keep newlines and indent the continuation lines instead.
+// Values with spaces, = or quotes are quoted so they can not forge fields,
+// spaces, = and quotes of keys are replaced by _.
+type TextEncoder struct {
+	Time      TimeFormat
+	Multiline bool
+}
+
+// Encode linter
+func (t *TextEncoder) Encode(e *Entry) ([]byte, error) {
+	return t.appendEntry(nil, e.Time, e.Level, e.Message, e.Fields), nil
+}
+
+func (t *TextEncoder) appendEntry(buf []byte, now time.Time, level Level, msg string, fields []Field) []byte {
+	buf = t.Time.appendTime(buf, now)
+	buf = append(buf, ' ')
+	buf = append(buf, level.String()...)
+	buf = append(buf, ' ')
+	buf = AppendEscaped(buf, msg, t.Multiline)
+	for _, field := range fields {
+		buf = append(buf, ' ')
+		buf = appendTextKey(buf, field.Key)
+		buf = append(buf, '=')
+		buf = appendEscapedValue(buf, field, t.Multiline)
+	}
+	return append(buf, '\n')
+}
+
+// appendTextKey append key escaped with spaces, = and quotes replaced by _
+func appendTextKey(buf []byte, key string) []byte {
+	if key == "" {
+		return append(buf, '_')
+	}
+	start := len(buf)
+	buf = AppendEscaped(buf, key, false)
+	for i := start; i < len(buf); i++ {
+		if c := buf[i]; c == ' ' || c == '=' || c == '"' {
+			buf[i] = '_'
+		}
+	}
+	return buf
+}
+
+// LogfmtEncoder write entries as logfmt
+//
+//	time=2006-01-02T15:04:05.000000000Z07:00 level=INFO msg="stream closed" stream_id=abc
+//
+// Values with spaces, quotes, = or control characters are quoted and escaped
+// like json strings, entries are always one line
+type LogfmtEncoder struct {
+	Time TimeFormat
+}
+
+// Encode linter
+func (l *LogfmtEncoder) Encode(e *Entry) ([]byte, error) {
+	return l.appendEntry(nil, e.Time, e.Level, e.Message, e.Fields), nil
+}
+
+func (l *LogfmtEncoder) appendEntry(buf []byte, now time.Time, level Level, msg string, fields []Field) []byte {
+	buf = append(buf, "time="...)
+	buf = l.Time.appendTime(buf, now)
+	buf = append(buf, " level="...)
+	buf = append(buf, level.String()...)
+	buf = append(buf, " msg="...)
+	buf = appendLogfmtValue(buf, msg)
+	for _, field := range fields {
+		buf = append(buf, ' ')
+		buf = appendLogfmtKey(buf, field.Key)
+		buf = append(buf, '=')
+		switch field.Type {
+		case IntType, DurationType:
+			buf = appendTextValue(buf, field)
+		default:
+			buf = appendLogfmtValue(buf, string(appendTextValue(nil, field)))
+		}
+	}
+	return append(buf, '\n')
+}
+
+// appendLogfmtKey append key with characters logfmt does not allow replaced by _
+func appendLogfmtKey(buf []byte, key string) []byte {
+	if key == "" {
+		return append(buf, '_')
+	}
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c <= ' ' || c == '=' || c == '"' || c >= utf8.RuneSelf {
+			buf = append(buf, '_')
+		} else {
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// appendLogfmtValue append s, quoted if needed
+func appendLogfmtValue(buf []byte, s string) []byte {
+	if s == "" {
+		return append(buf, `""`...)
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f || c >= utf8.RuneSelf {
+			return appendJSONString(buf, s)
+		}
+	}
+	return append(buf, s...)
+}
+
+// JSONEncoder write entries as one json object per line
+//
+//	{"time":"...","level":"INFO","msg":"message","key":"value"}
+//
+// Epoch time formats are written as numbers, entries are always one line
+type JSONEncoder struct {
+	Time TimeFormat
+}
+
+// Encode linter
+func (j *JSONEncoder) Encode(e *Entry) ([]byte, error) {
+	return j.appendEntry(nil, e.Time, e.Level, e.Message, e.Fields), nil
+}
+
+func (j *JSONEncoder) appendEntry(buf []byte, now time.Time, level Level, msg string, fields []Field) []byte {
+	buf = append(buf, `{"time":`...)
+	if n, ok := j.Time.epoch(now); ok {
+		buf = strconv.AppendInt(buf, n, 10)
+	} else {
+		buf = append(buf, '"')
+		buf = j.Time.appendTime(buf, now)
+		buf = append(buf, '"')
+	}
+	buf = append(buf, `,"level":"`...)
+	buf = append(buf, level.String()...)
+	buf = append(buf, `","msg":`...)
+	buf = appendJSONString(buf, msg)
+	for _, field := range fields {
+		buf = append(buf, ',')
+		buf = appendJSONString(buf, field.Key)
+		buf = append(buf, ':')
+		buf = appendJSONValue(buf, field)
+	}
+	return append(buf, "}\n"...)
+}
+
+// appendJSONValue append value of f, value which can not be marshaled is written as string
+func appendJSONValue(buf []byte, f Field) []byte {
+	switch f.Type {
+	case StringType:
+		return appendJSONString(buf, f.Str)
+	case IntType:
+		return strconv.AppendInt(buf, f.Int, 10)
+	case DurationType:
+		buf = append(buf, '"')
+		buf = appendDuration(buf, time.Duration(f.Int))
+		return append(buf, '"')
+	}
+
+	switch value := f.Value.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case string:
+		return appendJSONString(buf, value)
+	case int:
+		return strconv.AppendInt(buf, int64(value), 10)
+	case int64:
+		return strconv.AppendInt(buf, value, 10)
+	case bool:
+		return strconv.AppendBool(buf, value)
+	case error:
+		return appendJSONString(buf, value.Error())
+	}
+	v, err := json.Marshal(f.Value)
+	if err != nil {
+		return appendJSONString(buf, fmt.Sprint(f.Value))
+	}
+	return append(buf, v...)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString append s quoted and escaped, invalid utf8 is replaced by U+FFFD.
+// Like AppendEscaped, DEL, C1 controls and unicode line separators are written as \uNNNN.
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != 0x7f {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `�`...)
+			i += size
+			start = i
+			continue
+		}
+		if (r >= 0x80 && r <= 0x9f) || r == 0x2028 || r == 0x2029 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', hexDigits[r>>12], hexDigits[r>>8&0xf], hexDigits[r>>4&0xf], hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestTypedFields(t *testing.T) {
+	now := time.Date(2020, 9, 10, 1, 30, 15, 0, time.UTC)
+	fields := []Field{
+		String("stream_id", "a\"b"),
+		Int("size", 1200),
+		Duration("rtt", 1500*time.Millisecond),
+		Err(errors.New("closed")),
+		Any("ok", true),
+		Any("ids", []int{1, 2}),
+	}
+	e := &Entry{Time: now, Level: WarnLevel, Message: "line\nbreak", Fields: fields}
+
+	b, _ := (&JSONEncoder{}).Encode(e)
+	want := `{"time":"2020-09-10T01:30:15Z","level":"WARN","msg":"line\nbreak","stream_id":"a\"b","size":1200,"rtt":"1.5s","error":"closed","ok":true,"ids":[1,2]}` + "\n"
+	if string(b) != want {
+		t.Errorf("json\n got %s\nwant %s", b, want)
+	}
+
+	b, _ = (&TextEncoder{}).Encode(e)
+	want = "2020-09-10T01:30:15Z WARN line\\nbreak stream_id=\"a\\\"b\" size=1200 rtt=1.5s error=closed ok=true ids=\"[1 2]\"\n"
+	if string(b) != want {
+		t.Errorf("text\n got %q\nwant %q", b, want)
+	}
+
+	if v, _ := e.Field("rtt"); v != 1500*time.Millisecond {
+		t.Errorf("rtt = %v", v)
+	}
+}
+
+func TestJSONString(t *testing.T) {
+	got := string(appendJSONString(nil, "tab\t\x01é\xff"))
+	if want := `"tab\t\u0001é�"`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestWriterSinkLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewWriterSink(buf, &TextEncoder{Time: TimeFormat{Layout: TimeUnix}},
+		WithLevel(InfoLevel), WithClock(NewManualClock(time.Unix(10, 0))))
+	s.Debug("hidden")
+	s.DEBUG("hidden")
+	s.Info("shown", Int("n", 1))
+	s.SetLevel(ErrorLevel)
+	s.WARN("hidden")
+	s.ERROR("shown", 2)
+	s.PANIC("shown")
+	s.FATAL("shown")
+
+	want := "10 INFO shown n=1\n10 ERROR shown 2\n10 PANIC shown\n10 FATAL shown\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriterSinkAllocs(t *testing.T) {
+	s := NewWriterSink(ioutil.Discard, &JSONEncoder{}, WithLevel(InfoLevel))
+	err := errors.New("closed")
+
+	disabled := testing.AllocsPerRun(100, func() {
+		s.Debug("packet", String("stream_id", "abc"), Int("size", 1200), Err(err))
+	})
+	if disabled != 0 {
+		t.Errorf("disabled level allocs = %v, want 0", disabled)
+	}
+
+	enabled := testing.AllocsPerRun(100, func() {
+		s.Info("packet", String("stream_id", "abc"), Int("size", 1200), Duration("rtt", time.Millisecond), Err(err))
+	})
+	if enabled != 0 {
+		t.Errorf("json allocs = %v, want 0", enabled)
+	}
+}
+
+func benchmarkFields() []Field {
+	return []Field{
+		String("stream_id", "1hGQ7ZLpJ3Cb6pY0xM0t8HX2Zqw"),
+		Int("size", 1200),
+		Duration("rtt", 35*time.Millisecond),
+	}
+}
+
+func BenchmarkFactorLog(b *testing.B) {
+	l := NewFactorLog(WithOutput(ioutil.Discard), WithColor(ColorNever))
+	fields := benchmarkFields()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.INFO("packet forwarded", fields[0], fields[1], fields[2])
+	}
+}
+
+func BenchmarkWriterSinkJSON(b *testing.B) {
+	s := NewWriterSink(ioutil.Discard, &JSONEncoder{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Info("packet forwarded",
+			String("stream_id", "1hGQ7ZLpJ3Cb6pY0xM0t8HX2Zqw"),
+			Int("size", 1200),
+			Duration("rtt", 35*time.Millisecond))
+	}
+}
+
+func BenchmarkWriterSinkText(b *testing.B) {
+	s := NewWriterSink(ioutil.Discard, &TextEncoder{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Info("packet forwarded",
+			String("stream_id", "1hGQ7ZLpJ3Cb6pY0xM0t8HX2Zqw"),
+			Int("size", 1200),
+			Duration("rtt", 35*time.Millisecond))
+	}
+}
+
+func BenchmarkWriterSinkDisabled(b *testing.B) {
+	s := NewWriterSink(ioutil.Discard, &JSONEncoder{}, WithLevel(InfoLevel))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Debug("packet forwarded",
+			String("stream_id", "1hGQ7ZLpJ3Cb6pY0xM0t8HX2Zqw"),
+			Int("size", 1200),
+			Duration("rtt", 35*time.Millisecond))
+	}
+}
+
+func BenchmarkWriterSinkParallel(b *testing.B) {
+	s := NewWriterSink(ioutil.Discard, &JSONEncoder{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Info("packet forwarded", String("stream_id", "abc"), Int("size", 1200))
+		}
+	})
+}
+
+func BenchmarkFactorLogParallel(b *testing.B) {
+	l := NewFactorLog(WithOutput(ioutil.Discard), WithColor(ColorNever))
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.INFO("packet forwarded", String("stream_id", "abc"), Int("size", 1200))
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Level of a log entry
+type Level int8
+
+const (
+	// DebugLevel linter
+	DebugLevel Level = iota
+	// InfoLevel linter
+	InfoLevel
+	// WarnLevel linter
+	WarnLevel
+	// ErrorLevel linter
+	ErrorLevel
+	// PanicLevel is logged before a panic
+	PanicLevel
+	// FatalLevel is logged before the program exits
+	FatalLevel
+)
+
+// String return upper case name of level
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarnLevel:
+		return "WARN"
+	case ErrorLevel:
+		return "ERROR"
+	case PanicLevel:
+		return "PANIC"
+	case FatalLevel:
+		return "FATAL"
+	}
+	return fmt.Sprintf("LEVEL(%d)", l)
+}
+
+// ParseLevel return level of a name, case insensitive
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "DEBUG":
+		return DebugLevel, nil
+	case "INFO":
+		return InfoLevel, nil
+	case "WARN", "WARNING":
+		return WarnLevel, nil
+	case "ERROR":
+		return ErrorLevel, nil
+	case "PANIC":
+		return PanicLevel, nil
+	case "FATAL":
+		return FatalLevel, nil
+	}
+	return InfoLevel, fmt.Errorf("unknown level %q", s)
+}
+
+// Entry is a single log call
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  []Field
+}
+
+// NewEntry make an entry with values formatted like println
+// Field values are kept in Fields instead of the message
+func NewEntry(level Level, v ...interface{}) *Entry {
+	e := &Entry{
+		Time:  time.Now(),
+		Level: level,
+	}
+
+	values := v
+	for i, value := range v {
+		if _, ok := value.(Field); ok {
+			// copy only when there are fields
+			values = make([]interface{}, 0, len(v))
+			values = append(values, v[:i]...)
+			for _, value := range v[i:] {
+				if field, ok := value.(Field); ok {
+					e.Fields = append(e.Fields, field)
+				} else {
+					values = append(values, value)
+				}
+			}
+			break
+		}
+	}
+	e.Message = strings.TrimSuffix(fmt.Sprintln(values...), "\n")
+	return e
+}
+
+// Args return message and fields as values of a Log method
+func (e *Entry) Args() []interface{} {
+	args := make([]interface{}, 0, len(e.Fields)+1)
+	args = append(args, e.Message)
+	for _, field := range e.Fields {
+		args = append(args, field)
+	}
+	return args
+}
+
+// Text return message followed by fields as key=value
+func (e *Entry) Text() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for _, field := range e.Fields {
+		b.WriteByte(' ')
+		b.WriteString(field.String())
+	}
+	return b.String()
+}
+
+// Field return value of key and true if the entry has it
+func (e *Entry) Field(key string) (interface{}, bool) {
+	for _, field := range e.Fields {
+		if field.Key == key {
+			return field.Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// EntryWriter is implemented by logs which can write a prepared entry
+// keeping its time instead of stamping a new one.
+// Prepared entries are written as is, without level filter or sampling.
+type EntryWriter interface {
+	WriteEntry(e *Entry) error
+}
+
+// WriteEntry write e into l, fallback to the level method of l
+func WriteEntry(l Log, e *Entry) error {
+	if w, ok := l.(EntryWriter); ok {
+		return w.WriteEntry(e)
+	}
+	Emit(l, e.Level, e.Args()...)
+	return nil
+}
+
+// EntryLogger is implemented by logs taking live entries built with typed
+// fields. Unlike prepared entries of WriteEntry, they are filtered, sampled
+// and dumped like the entries of the level methods.
+type EntryLogger interface {
+	LogEntry(e *Entry)
+}
+
+// LogEntry log the live entry e into l, fallback to the level method of l
+func LogEntry(l Log, e *Entry) {
+	if w, ok := l.(EntryLogger); ok {
+		w.LogEntry(e)
+		return
+	}
+	Emit(l, e.Level, e.Args()...)
+}
+
+// Syncer is implemented by logs buffering entries, Sync return once they are written
+type Syncer interface {
+	Sync() error
+}
+
+// Sync write entries buffered by l, nothing to do if l is not a Syncer
+func Sync(l Log) error {
+	if s, ok := l.(Syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// SyncWriter commit out to storage if it can, like an os.File or a RotatingFile.
+// Files which are not regular, like stdout on a pipe or a tty, have nothing to commit.
+func SyncWriter(out io.Writer) error {
+	if f, ok := out.(*os.File); ok {
+		if info, err := f.Stat(); err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+	}
+	if s, ok := out.(Syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Emit call the method of l match with level
+func Emit(l Log, level Level, v ...interface{}) {
+	switch level {
+	case DebugLevel:
+		l.DEBUG(v...)
+	case InfoLevel:
+		l.INFO(v...)
+	case WarnLevel:
+		l.WARN(v...)
+	case PanicLevel:
+		l.PANIC(v...)
+	case FatalLevel:
+		l.FATAL(v...)
+	default:
+		l.ERROR(v...)
+	}
+}
+
+// leveled implement level methods of Log with one func
+type leveled func(level Level, v ...interface{})
+
+// ERROR linter
+func (f leveled) ERROR(v ...interface{}) {
+	f(ErrorLevel, v...)
+}
+
+// INFO linter
+func (f leveled) INFO(v ...interface{}) {
+	f(InfoLevel, v...)
+}
+
+// WARN linter
+func (f leveled) WARN(v ...interface{}) {
+	f(WarnLevel, v...)
+}
+
+// DEBUG linter
+func (f leveled) DEBUG(v ...interface{}) {
+	f(DebugLevel, v...)
+}
+
+// PANIC linter
+func (f leveled) PANIC(v ...interface{}) {
+	f(PanicLevel, v...)
+}
+
+// FATAL linter
+func (f leveled) FATAL(v ...interface{}) {
+	f(FatalLevel, v...)
+}
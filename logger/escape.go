@@ -0,0 +1,61 @@
+package logger
+
+import "unicode/utf8"
+
+// continuationIndent start continuation lines in multi-line mode
+const continuationIndent = "    "
+
+// AppendEscaped append s so it can not forge log lines or terminal output.
+// Newlines, carriage returns and tabs are written as \n, \r and \t, other
+// control characters, like the ESC of ANSI sequences, and invalid utf8 bytes
+// as \xNN, C1 controls and unicode line separators as \uNNNN.
+// With multiline, newlines are kept followed by an indent and tabs are kept.
+func AppendEscaped(buf []byte, s string, multiline bool) []byte {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= 0x20 && c < 0x7f {
+			i++
+			continue
+		}
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				buf = append(buf, s[start:i]...)
+				buf = append(buf, '\\', 'x', hexDigits[c>>4], hexDigits[c&0xf])
+				i++
+				start = i
+				continue
+			}
+			if (r < 0x80 || r > 0x9f) && r != 0x2028 && r != 0x2029 {
+				i += size
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', hexDigits[r>>12], hexDigits[r>>8&0xf], hexDigits[r>>4&0xf], hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+
+		buf = append(buf, s[start:i]...)
+		switch {
+		case c == '\n' && multiline:
+			buf = append(buf, '\n')
+			buf = append(buf, continuationIndent...)
+		case c == '\t' && multiline:
+			buf = append(buf, '\t')
+		case c == '\n':
+			buf = append(buf, '\\', 'n')
+		case c == '\r':
+			buf = append(buf, '\\', 'r')
+		case c == '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			buf = append(buf, '\\', 'x', hexDigits[c>>4], hexDigits[c&0xf])
+		}
+		i++
+		start = i
+	}
+	return append(buf, s[start:]...)
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestAppendEscaped(t *testing.T) {
+	tests := []struct {
+		in, want, multiline string
+	}{
+		{"plain é 日本", "plain é 日本", "plain é 日本"},
+		{"client-1\n[ERROR] forged", `client-1\n[ERROR] forged`, "client-1\n    [ERROR] forged"},
+		{"a\r\n\tb", `a\r\n\tb`, "a\\r\n    \tb"},
+		{"\x1b[31mred\x1b[0m", `\x1b[31mred\x1b[0m`, `\x1b[31mred\x1b[0m`},
+		{"nul\x00del\x7f", `nul\x00del\x7f`, `nul\x00del\x7f`},
+		{"c1\u0085sep\u2028", `c1\u0085sep\u2028`, `c1\u0085sep\u2028`},
+		{"bad\xff", `bad\xff`, `bad\xff`},
+	}
+	for _, tt := range tests {
+		if got := string(AppendEscaped(nil, tt.in, false)); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+		if got := string(AppendEscaped(nil, tt.in, true)); got != tt.multiline {
+			t.Errorf("%q multiline: got %q, want %q", tt.in, got, tt.multiline)
+		}
+	}
+}
+
+func TestTextEncoderEscape(t *testing.T) {
+	e := &Entry{
+		Time:    time.Unix(10, 0),
+		Level:   ErrorLevel,
+		Message: "register\nfailed",
+		Fields:  []Field{String("client\nid", "a\n[INFO] b"), Any("value", "\x1b[2J")},
+	}
+	b, _ := (&TextEncoder{Time: TimeFormat{Layout: TimeUnix}}).Encode(e)
+	if want := `10 ERROR register\nfailed client\nid="a\n[INFO] b" value=\x1b[2J` + "\n"; string(b) != want {
+		t.Errorf("got %q, want %q", b, want)
+	}
+
+	b, _ = (&TextEncoder{Time: TimeFormat{Layout: TimeUnix}, Multiline: true}).Encode(e)
+	if want := "10 ERROR register\n    failed client\\nid=\"a\n    [INFO] b\" value=\\x1b[2J\n"; string(b) != want {
+		t.Errorf("multiline got %q, want %q", b, want)
+	}
+}
+
+func TestFactorLogEscape(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewFactorLog(WithOutput(buf), WithFormat(`%{SEVERITY} %{Message}`))
+	l.INFO("Remove id ", "a\n[ERROR] forged")
+	if want := `INFO Remove id a\n[ERROR] forged` + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	l = NewFactorLog(WithOutput(buf), WithFormat(`%{SEVERITY} %{Message}`), WithMultiline())
+	l.WARN("first\nsecond")
+	if want := "WARN first\n    second\n"; buf.String() != want {
+		t.Errorf("multiline got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestJSONStringEscape(t *testing.T) {
+	in := "a\x7fb\u009b[2Jc\u2028d\u2029"
+	if got, want := string(appendJSONString(nil, in)), `"a\u007fb\u009b[2Jc\u2028d\u2029"`; got != want {
+		t.Errorf("json got %s, want %s", got, want)
+	}
+	if got, want := string(appendLogfmtValue(nil, in)), `"a\u007fb\u009b[2Jc\u2028d\u2029"`; got != want {
+		t.Errorf("logfmt got %s, want %s", got, want)
+	}
+}
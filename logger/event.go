@@ -0,0 +1,363 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventKey is the field key of the event name
+const EventKey = "event"
+
+// SchemaErrorKey is the field key of schema violations added in SchemaAnnotate mode
+const SchemaErrorKey = "schema_error"
+
+// Event return the field of an event name, entries with it are checked
+// against the schema of the event:
+//
+//	l.INFO("stream closed", logger.Event("stream.closed"), logger.String("stream_id", id))
+func Event(name string) Field {
+	return String(EventKey, name)
+}
+
+// FieldSpec declare a field of an event
+type FieldSpec struct {
+	Key      string    `json:"key"`
+	Type     FieldType `json:"-"`
+	Required bool      `json:"required"`
+	Doc      string    `json:"doc,omitempty"`
+}
+
+// Required return the spec of a field every entry of the event must have
+func Required(key string, t FieldType, doc string) FieldSpec {
+	return FieldSpec{Key: key, Type: t, Required: true, Doc: doc}
+}
+
+// Optional return the spec of a field an entry of the event may have
+func Optional(key string, t FieldType, doc string) FieldSpec {
+	return FieldSpec{Key: key, Type: t, Doc: doc}
+}
+
+// MarshalJSON write the type by name
+func (s FieldSpec) MarshalJSON() ([]byte, error) {
+	type spec FieldSpec
+	return json.Marshal(struct {
+		spec
+		Type string `json:"type"`
+	}{spec(s), s.Type.String()})
+}
+
+// check return the problem of a field value, empty if it has the type
+func (s *FieldSpec) check(f Field) string {
+	ok := true
+	value := f.Interface()
+	switch s.Type {
+	case StringType:
+		_, ok = value.(string)
+	case IntType:
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			ok = false
+		}
+	case DurationType:
+		_, ok = value.(time.Duration)
+	case ErrorType:
+		_, ok = value.(error)
+	}
+	if ok {
+		return ""
+	}
+	return fmt.Sprintf("field %s is %T, want %s", s.Key, value, s.Type)
+}
+
+// EventSchema declare an event and its fields, fields not declared are allowed
+type EventSchema struct {
+	Name   string      `json:"name"`
+	Doc    string      `json:"doc,omitempty"`
+	Fields []FieldSpec `json:"fields"`
+}
+
+// equal check both schemas declare the same event
+func (s *EventSchema) equal(other *EventSchema) bool {
+	return reflect.DeepEqual(s, other)
+}
+
+// SchemaError list what an entry miss to match the schema of its event
+type SchemaError struct {
+	Event    string
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("event %q: %s", e.Event, strings.Join(e.Problems, ", "))
+}
+
+// EventRegistry is the catalogue of events a service emit
+type EventRegistry struct {
+	events *AdvanceMap // name - *EventSchema
+	mutex  sync.Mutex  // serialize Register
+}
+
+// NewEventRegistry return an empty registry
+func NewEventRegistry() *EventRegistry {
+	return &EventRegistry{
+		events: NewAdvanceMap(),
+	}
+}
+
+// Register add an event and return its field. Registering the same schema
+// again is allowed, a different schema of a registered name is an error.
+func (r *EventRegistry) Register(schema EventSchema) (Field, error) {
+	if schema.Name == "" {
+		return Field{}, fmt.Errorf("event without name")
+	}
+	keys := make(map[string]bool)
+	for _, spec := range schema.Fields {
+		if spec.Key == "" || spec.Key == EventKey || keys[spec.Key] {
+			return Field{}, fmt.Errorf("event %q: invalid field %q", schema.Name, spec.Key)
+		}
+		keys[spec.Key] = true
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if old, ok := r.Lookup(schema.Name); ok && !old.equal(&schema) {
+		return Field{}, fmt.Errorf("event %q is already registered with another schema", schema.Name)
+	}
+	r.events.Set(schema.Name, &schema)
+	return Event(schema.Name), nil
+}
+
+// MustRegister is like Register but panics on error, for package variables:
+//
+//	var streamClosed = registry.MustRegister(logger.EventSchema{Name: "stream.closed", ...})
+func (r *EventRegistry) MustRegister(schema EventSchema) Field {
+	field, err := r.Register(schema)
+	if err != nil {
+		panic(err)
+	}
+	return field
+}
+
+// Lookup return the schema of an event name
+func (r *EventRegistry) Lookup(name string) (*EventSchema, bool) {
+	if value, ok := r.events.Get(name); ok {
+		schema, ok := value.(*EventSchema)
+		return schema, ok
+	}
+	return nil, false
+}
+
+// Len return number of registered events
+func (r *EventRegistry) Len() int {
+	return r.events.Len()
+}
+
+// Events return every schema sorted by name
+func (r *EventRegistry) Events() []*EventSchema {
+	names := r.events.GetKeys()
+	sort.Strings(names)
+	events := make([]*EventSchema, 0, len(names))
+	for _, name := range names {
+		if schema, ok := r.Lookup(name); ok {
+			events = append(events, schema)
+		}
+	}
+	return events
+}
+
+// Validate check e against the schema of its event, nil if e has no event field
+func (r *EventRegistry) Validate(e *Entry) error {
+	value, ok := e.Field(EventKey)
+	if !ok {
+		return nil
+	}
+	name := fmt.Sprint(value)
+	schema, ok := r.Lookup(name)
+	if !ok {
+		return &SchemaError{Event: name, Problems: []string{"event is not registered"}}
+	}
+
+	var problems []string
+	for i := range schema.Fields {
+		spec := &schema.Fields[i]
+		found := false
+		for _, field := range e.Fields {
+			if field.Key != spec.Key {
+				continue
+			}
+			found = true
+			if problem := spec.check(field); problem != "" {
+				problems = append(problems, problem)
+			}
+			break
+		}
+		if !found && spec.Required {
+			problems = append(problems, "missing field "+spec.Key)
+		}
+	}
+	if len(problems) > 0 {
+		return &SchemaError{Event: name, Problems: problems}
+	}
+	return nil
+}
+
+// Handler return the catalogue of events, as JSON or as a markdown document
+// with ?format=markdown
+func (r *EventRegistry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("format") == "markdown" {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			w.Write([]byte(r.Markdown()))
+			return
+		}
+		data, err := json.MarshalIndent(r.Events(), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(data, '\n'))
+	})
+}
+
+// Markdown return the catalogue of events as a markdown document
+func (r *EventRegistry) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Events\n")
+	for _, schema := range r.Events() {
+		fmt.Fprintf(&b, "\n## %s\n\n", schema.Name)
+		if schema.Doc != "" {
+			b.WriteString(schema.Doc + "\n\n")
+		}
+		if len(schema.Fields) == 0 {
+			continue
+		}
+		b.WriteString("| Field | Type | Required | Description |\n|---|---|---|---|\n")
+		for _, spec := range schema.Fields {
+			required := "no"
+			if spec.Required {
+				required = "yes"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", spec.Key, spec.Type, required, strings.Replace(spec.Doc, "|", `\|`, -1))
+		}
+	}
+	return b.String()
+}
+
+// SchemaMode is what a SchemaLog does with entries not matching their schema
+type SchemaMode int
+
+const (
+	// SchemaAnnotate write the entry with a schema_error field
+	SchemaAnnotate SchemaMode = iota
+	// SchemaStrict drop the entry and write an ERROR entry describing the violation,
+	// with the message of the entry as dropped and its fields
+	SchemaStrict
+	// SchemaOff do not check entries
+	SchemaOff
+)
+
+// ParseSchemaMode return mode of annotate, strict or off
+func ParseSchemaMode(s string) (SchemaMode, error) {
+	switch strings.ToLower(s) {
+	case "annotate":
+		return SchemaAnnotate, nil
+	case "strict":
+		return SchemaStrict, nil
+	case "off":
+		return SchemaOff, nil
+	}
+	return SchemaAnnotate, fmt.Errorf("unknown schema mode %q", s)
+}
+
+// SchemaLog check entries with an event field against the registry before next
+type SchemaLog struct {
+	leveled
+	next     Log
+	registry *EventRegistry
+	mode     SchemaMode
+}
+
+// NewSchemaLog return a schema check in front of next
+func NewSchemaLog(next Log, registry *EventRegistry, mode SchemaMode) *SchemaLog {
+	s := &SchemaLog{
+		next:     next,
+		registry: registry,
+		mode:     mode,
+	}
+	s.leveled = s.log
+	return s
+}
+
+// STACK linter
+func (s *SchemaLog) STACK(v ...string) {
+	s.next.STACK(v...)
+}
+
+// WriteEntry write e, annotated or replaced by the violation if it does not
+// match its schema. In SchemaStrict mode the violation is returned.
+func (s *SchemaLog) WriteEntry(e *Entry) error {
+	checked, err := s.check(e)
+	if werr := WriteEntry(s.next, checked); werr != nil {
+		return werr
+	}
+	return err
+}
+
+// LogEntry log e, annotated or replaced by the violation
+func (s *SchemaLog) LogEntry(e *Entry) {
+	checked, _ := s.check(e)
+	LogEntry(s.next, checked)
+}
+
+// log pass v as is unless it has an event field to check
+func (s *SchemaLog) log(level Level, v ...interface{}) {
+	if s.mode == SchemaOff || s.registry.Len() == 0 || !hasEventField(v) {
+		Emit(s.next, level, v...)
+		return
+	}
+	e, _ := s.check(NewEntry(level, v...))
+	Emit(s.next, e.Level, e.Args()...)
+}
+
+// hasEventField return true if v has a Field of key EventKey
+func hasEventField(v []interface{}) bool {
+	for _, arg := range v {
+		if field, ok := arg.(Field); ok && field.Key == EventKey {
+			return true
+		}
+	}
+	return false
+}
+
+// check return the entry to write and the violation in SchemaStrict mode
+func (s *SchemaLog) check(e *Entry) (*Entry, error) {
+	if s.mode == SchemaOff || s.registry.Len() == 0 {
+		return e, nil
+	}
+	err := s.registry.Validate(e)
+	if err == nil {
+		return e, nil
+	}
+	if s.mode == SchemaStrict {
+		violation := &Entry{
+			Time:    e.Time,
+			Level:   ErrorLevel,
+			Message: "schema violation: " + err.Error(),
+			// fields are kept apart so the redactor after the check still masks them
+			Fields: append([]Field{String("dropped", e.Message)}, e.Fields...),
+		}
+		return violation, err
+	}
+	annotated := *e
+	annotated.Fields = append(append([]Field(nil), e.Fields...), String(SchemaErrorKey, err.Error()))
+	return &annotated, nil
+}
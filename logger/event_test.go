@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var streamClosedSchema = EventSchema{
+	Name: "stream.closed",
+	Doc:  "A stream was closed by the client or a timeout",
+	Fields: []FieldSpec{
+		Required("stream_id", StringType, "id of the stream"),
+		Optional("rtt", DurationType, "last round trip time"),
+	},
+}
+
+func TestEventRegistry(t *testing.T) {
+	r := NewEventRegistry()
+	event := r.MustRegister(streamClosedSchema)
+	if _, err := r.Register(streamClosedSchema); err != nil {
+		t.Errorf("same schema again: %v", err)
+	}
+	other := streamClosedSchema
+	other.Fields = nil
+	if _, err := r.Register(other); err == nil {
+		t.Error("expected an error for another schema")
+	}
+	if _, err := r.Register(EventSchema{Name: "x", Fields: []FieldSpec{Optional("a", IntType, ""), Optional("a", IntType, "")}}); err == nil {
+		t.Error("expected an error for a duplicate field")
+	}
+
+	tests := []struct {
+		fields []Field
+		want   string
+	}{
+		{[]Field{String("stream_id", "a")}, ""},
+		{[]Field{Int("stream_id", 1)}, "field stream_id is int64, want string"},
+		{[]Field{String("stream_id", "a"), Any("rtt", 3)}, "field rtt is int, want duration"},
+		{[]Field{Duration("rtt", time.Second)}, "missing field stream_id"},
+		{nil, "missing field stream_id"},
+	}
+	for _, tt := range tests {
+		e := &Entry{Fields: append([]Field{event}, tt.fields...)}
+		err := r.Validate(e)
+		var got string
+		var serr *SchemaError
+		if errors.As(err, &serr) {
+			got = strings.Join(serr.Problems, ", ")
+		}
+		if got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.fields, got, tt.want)
+		}
+	}
+
+	if err := r.Validate(&Entry{Fields: []Field{Event("stream.opened")}}); err == nil {
+		t.Error("expected an error for an unregistered event")
+	}
+	if err := r.Validate(&Entry{Message: "free form"}); err != nil {
+		t.Errorf("entry without event: %v", err)
+	}
+}
+
+func TestSchemaLog(t *testing.T) {
+	r := NewEventRegistry()
+	event := r.MustRegister(streamClosedSchema)
+	enc := &TextEncoder{Time: TimeFormat{Layout: TimeUnix}}
+
+	buf := &syncBuffer{}
+	annotate := NewSchemaLog(NewWriterSink(buf, enc), r, SchemaAnnotate)
+	annotate.INFO("closed", event, String("stream_id", "a"))
+	annotate.INFO("closed", event)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || strings.Contains(lines[0], SchemaErrorKey) ||
+		!strings.HasSuffix(lines[1], `closed event=stream.closed schema_error="event \"stream.closed\": missing field stream_id"`) {
+		t.Errorf("annotate: %q", lines)
+	}
+
+	buf = &syncBuffer{}
+	strict := NewSchemaLog(NewWriterSink(buf, enc), r, SchemaStrict)
+	err := strict.WriteEntry(&Entry{Time: time.Unix(10, 0), Level: InfoLevel, Message: "closed", Fields: []Field{event}})
+	if err == nil {
+		t.Error("strict: expected an error")
+	}
+	want := `10 ERROR schema violation: event "stream.closed": missing field stream_id dropped=closed event=stream.closed` + "\n"
+	if buf.String() != want {
+		t.Errorf("strict: got %q, want %q", buf.String(), want)
+	}
+
+	// the fields of the dropped entry are still redacted by key
+	buf = &syncBuffer{}
+	redacted := NewSchemaLog(NewRedactor(NewWriterSink(buf, enc), NewRedaction().Field(RedactFull, "password")), r, SchemaStrict)
+	redacted.INFO("closed", event, String("password", "hunter2"))
+	if got := buf.String(); strings.Contains(got, "hunter2") || !strings.Contains(got, "password=") {
+		t.Errorf("strict redaction: %q", got)
+	}
+}
+
+func TestEventCatalogue(t *testing.T) {
+	r := NewEventRegistry()
+	r.MustRegister(streamClosedSchema)
+	r.MustRegister(EventSchema{Name: "client.added"})
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	var events []struct {
+		Name   string
+		Fields []struct {
+			Key      string
+			Type     string
+			Required bool
+		}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Name != "client.added" || events[1].Fields[1].Type != "duration" || !events[1].Fields[0].Required {
+		t.Errorf("catalogue %+v", events)
+	}
+
+	w = httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/?format=markdown", nil))
+	if !strings.Contains(w.Body.String(), "| stream_id | string | yes | id of the stream |") {
+		t.Errorf("markdown %s", w.Body.String())
+	}
+}
+
+func TestSchemaLogSkip(t *testing.T) {
+	buf := &syncBuffer{}
+	enc := &TextEncoder{Time: TimeFormat{Layout: TimeUnix}}
+
+	// nothing registered, events are not checked
+	empty := NewSchemaLog(NewWriterSink(buf, enc), NewEventRegistry(), SchemaStrict)
+	empty.INFO("closed", Event("unknown"))
+	if strings.Contains(buf.String(), "schema") {
+		t.Errorf("empty registry: %q", buf.String())
+	}
+
+	r := NewEventRegistry()
+	r.MustRegister(EventSchema{Name: "stream.closed"})
+	buf = &syncBuffer{}
+	strict := NewSchemaLog(NewWriterSink(buf, enc), r, SchemaStrict)
+	strict.INFO("no event", String("k", "v"))
+	strict.INFO("closed", Event("unknown"))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "INFO no event k=v") || !strings.Contains(lines[1], "schema violation") {
+		t.Errorf("strict: %q", lines)
+	}
+}
@@ -0,0 +1,218 @@
+package logger
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType tell which member of a Field hold its value
+type FieldType uint8
+
+const (
+	// AnyType value is in Value, encoded with fmt or encoding/json
+	AnyType FieldType = iota
+	// StringType value is in Str
+	StringType
+	// IntType value is in Int
+	IntType
+	// DurationType value is in Int as nanoseconds
+	DurationType
+	// ErrorType value is an error in Value
+	ErrorType
+)
+
+// String return lower case name of type
+func (t FieldType) String() string {
+	switch t {
+	case AnyType:
+		return "any"
+	case StringType:
+		return "string"
+	case IntType:
+		return "int"
+	case DurationType:
+		return "duration"
+	case ErrorType:
+		return "error"
+	}
+	return fmt.Sprintf("type(%d)", t)
+}
+
+// Field is a key value attached to an entry.
+// Fields are passed to a Log like other values and picked out by NewEntry:
+//
+//	l.INFO("client added", logger.Any("client_id", id))
+//
+// Typed fields (String, Int, Duration, Err) keep their value unboxed so
+// encoders can append them without allocation.
+type Field struct {
+	Key   string
+	Type  FieldType
+	Int   int64
+	Str   string
+	Value interface{}
+}
+
+// Any return a field of key and value
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// String return a string field
+func String(key, value string) Field {
+	return Field{Key: key, Type: StringType, Str: value}
+}
+
+// Int return an int field
+func Int(key string, value int) Field {
+	return Field{Key: key, Type: IntType, Int: int64(value)}
+}
+
+// Int64 return an int64 field
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Type: IntType, Int: value}
+}
+
+// Duration return a duration field, written as seconds like 1.5s
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Type: DurationType, Int: int64(value)}
+}
+
+// ErrorKey is the field key of Err
+const ErrorKey = "error"
+
+// Err return an error field of key "error"
+func Err(err error) Field {
+	return Field{Key: ErrorKey, Type: ErrorType, Value: err}
+}
+
+// Interface return value of the field whatever its type
+func (f Field) Interface() interface{} {
+	switch f.Type {
+	case StringType:
+		return f.Str
+	case IntType:
+		return f.Int
+	case DurationType:
+		return time.Duration(f.Int)
+	}
+	return f.Value
+}
+
+// String format field as key=value
+func (f Field) String() string {
+	return string(appendTextField(nil, f))
+}
+
+// CallerKey is the field key of the log call location
+const CallerKey = "caller"
+
+// Caller return a field of the file:line calling the function skip frames above
+// Caller(0) is the line calling Caller
+func Caller(skip int) Field {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return String(CallerKey, "???")
+	}
+	// keep package dir and file name
+	short := file
+	for i, n := len(file)-1, 0; i > 0; i-- {
+		if file[i] == '/' {
+			if n++; n == 2 {
+				short = file[i+1:]
+				break
+			}
+		}
+	}
+	return String(CallerKey, short+":"+strconv.Itoa(line))
+}
+
+// appendTextField append key=value, quoted like the TextEncoder so a value can
+// not forge fields. Control characters are escaped by the writer of the line.
+func appendTextField(buf []byte, f Field) []byte {
+	buf = appendTextKey(buf, f.Key)
+	buf = append(buf, '=')
+	switch f.Type {
+	case StringType:
+		return appendQuoted(buf, f.Str)
+	case IntType, DurationType:
+		return appendTextValue(buf, f)
+	}
+	return appendQuoted(buf, string(appendTextValue(nil, f)))
+}
+
+// appendTextValue append value of f as text
+func appendTextValue(buf []byte, f Field) []byte {
+	switch f.Type {
+	case StringType:
+		return append(buf, f.Str...)
+	case IntType:
+		return strconv.AppendInt(buf, f.Int, 10)
+	case DurationType:
+		return appendDuration(buf, time.Duration(f.Int))
+	case ErrorType:
+		if err, ok := f.Value.(error); ok && err != nil {
+			return append(buf, err.Error()...)
+		}
+	}
+	return append(buf, fmt.Sprint(f.Value)...)
+}
+
+// appendEscapedValue append value of f escaped, see AppendEscaped, and quoted if needed
+func appendEscapedValue(buf []byte, f Field, multiline bool) []byte {
+	switch f.Type {
+	case StringType:
+		return appendQuotedText(buf, f.Str, multiline)
+	case IntType, DurationType:
+		return appendTextValue(buf, f)
+	}
+	return appendQuotedText(buf, string(appendTextValue(nil, f)), multiline)
+}
+
+// appendQuotedText append s escaped, quoted if it has spaces, = or quotes.
+// Quotes and backslashes of a quoted value are escaped with a backslash.
+func appendQuotedText(buf []byte, s string, multiline bool) []byte {
+	if !strings.ContainsAny(s, " =\"") {
+		return AppendEscaped(buf, s, multiline)
+	}
+	buf = append(buf, '"')
+	for {
+		i := strings.IndexAny(s, "\"\\")
+		if i < 0 {
+			break
+		}
+		buf = AppendEscaped(buf, s[:i], multiline)
+		buf = append(buf, '\\', s[i])
+		s = s[i+1:]
+	}
+	buf = AppendEscaped(buf, s, multiline)
+	return append(buf, '"')
+}
+
+// appendQuoted append s quoted like appendQuotedText, without escaping it
+func appendQuoted(buf []byte, s string) []byte {
+	if !strings.ContainsAny(s, " =\"") {
+		return append(buf, s...)
+	}
+	buf = append(buf, '"')
+	for {
+		i := strings.IndexAny(s, "\"\\")
+		if i < 0 {
+			break
+		}
+		buf = append(buf, s[:i]...)
+		buf = append(buf, '\\', s[i])
+		s = s[i+1:]
+	}
+	buf = append(buf, s...)
+	return append(buf, '"')
+}
+
+// appendDuration append d as seconds with unit, readable by time.ParseDuration
+func appendDuration(buf []byte, d time.Duration) []byte {
+	buf = strconv.AppendFloat(buf, d.Seconds(), 'f', -1, 64)
+	return append(buf, 's')
+}
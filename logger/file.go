@@ -0,0 +1,296 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatedTimeFormat is the time suffix of rotated files, followed by -<n>
+// when a file of the same time exists
+const RotatedTimeFormat = "20060102T150405.000"
+
+// FileConfig config a RotatingFile
+type FileConfig struct {
+	Path     string        // current file, rotated files are Path.<time>[-<n>][.gz]
+	MaxSize  int64         // rotate before the file grows over MaxSize bytes, 0 disable
+	Interval time.Duration // rotate when the clock enters a new interval (e.g. 24h, UTC based), 0 disable
+	Compress bool          // gzip rotated files
+	Clock    Clock         // default SystemClock
+	OnError  func(error)   // called with rotation errors of Write and compression errors, default print on stderr
+}
+
+// RotatingFile is a file writer rotating by size and time
+type RotatingFile struct {
+	cfg    FileConfig
+	file   *os.File
+	size   int64
+	period time.Time // start of current interval
+	wg     sync.WaitGroup
+	mutex  sync.Mutex
+}
+
+// NewRotatingFile open cfg.Path for append, create its directory if needed
+func NewRotatingFile(cfg FileConfig) (*RotatingFile, error) {
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = reportWriteError
+	}
+	f := &RotatingFile{cfg: cfg}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path return path of current file
+func (f *RotatingFile) Path() string {
+	return f.cfg.Path
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.period = f.currentPeriod()
+	return nil
+}
+
+func (f *RotatingFile) currentPeriod() time.Time {
+	if f.cfg.Interval <= 0 {
+		return time.Time{}
+	}
+	return f.cfg.Clock.Now().Truncate(f.cfg.Interval)
+}
+
+// Write linter
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return 0, err
+			}
+			// the file was reopened, keep the entry in it
+			f.cfg.OnError(err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.cfg.MaxSize > 0 && f.size > 0 && f.size+n > f.cfg.MaxSize {
+		return true
+	}
+	return f.cfg.Interval > 0 && !f.currentPeriod().Equal(f.period)
+}
+
+// Rotate close current file, rename it with the time and open a new one
+func (f *RotatingFile) Rotate() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// rotate rename the current file, it is reopened to keep writing if that fails
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	rotated := f.rotatedName()
+	if err := os.Rename(f.cfg.Path, rotated); err != nil {
+		if oerr := f.open(); oerr != nil {
+			return fmt.Errorf("rotate %s: %v, reopen: %w", f.cfg.Path, err, oerr)
+		}
+		return err
+	}
+	if f.cfg.Compress {
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			if err := compressFile(rotated); err != nil {
+				f.cfg.OnError(fmt.Errorf("compress %s: %w", rotated, err))
+			}
+		}()
+	}
+	return f.open()
+}
+
+// rotatedName return the name of the current file rotated now, not used by
+// a file rotated in the same millisecond
+func (f *RotatingFile) rotatedName() string {
+	name := f.cfg.Path + "." + f.cfg.Clock.Now().Format(RotatedTimeFormat)
+	rotated := name
+	for n := 1; exists(rotated) || exists(rotated+".gz"); n++ {
+		rotated = name + "-" + strconv.Itoa(n)
+	}
+	return rotated
+}
+
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+// compressFile gzip path into path.gz and remove path
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Sync commit current file to disk
+func (f *RotatingFile) Sync() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.file.Sync()
+}
+
+// Close current file and wait for compression
+func (f *RotatingFile) Close() error {
+	f.mutex.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mutex.Unlock()
+	f.wg.Wait()
+	return err
+}
+
+// RotatedFiles return rotated files of path oldest first then path if it exists,
+// a file being compressed is read uncompressed
+func RotatedFiles(path string) []string {
+	type rotated struct {
+		name string
+		time time.Time
+		n    int
+	}
+	matches, _ := filepath.Glob(path + ".*")
+	var files []rotated
+	for _, name := range matches {
+		t, n, ok := parseRotatedSuffix(strings.TrimSuffix(name[len(path)+1:], ".gz"))
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(name, ".gz") && exists(strings.TrimSuffix(name, ".gz")) {
+			continue
+		}
+		files = append(files, rotated{name, t, n})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].time.Equal(files[j].time) {
+			return files[i].time.Before(files[j].time)
+		}
+		return files[i].n < files[j].n
+	})
+
+	names := make([]string, 0, len(files)+1)
+	for _, file := range files {
+		names = append(names, file.name)
+	}
+	if _, err := os.Stat(path); err == nil {
+		names = append(names, path)
+	}
+	return names
+}
+
+// parseRotatedSuffix return the rotation time and number of a rotated file suffix
+func parseRotatedSuffix(suffix string) (time.Time, int, bool) {
+	n := 0
+	if i := strings.LastIndexByte(suffix, '-'); i > 0 {
+		var err error
+		if n, err = strconv.Atoi(suffix[i+1:]); err != nil || n <= 0 {
+			return time.Time{}, 0, false
+		}
+		suffix = suffix[:i]
+	}
+	t, err := time.Parse(RotatedTimeFormat, suffix)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return t, n, true
+}
+
+// OpenLogFile open a log file, gunzip it if name ends with .gz
+func OpenLogFile(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &gzipFile{zr, f}, nil
+}
+
+// gzipFile close the gzip reader and its file
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldExpr compare a field of entries with a value
+type FieldExpr struct {
+	Key    string
+	Op     string // =, !=, ~ (regexp), !~, >, >=, <, <=
+	Value  string
+	regexp *regexp.Regexp
+}
+
+// exprOps are tried in order so two characters ops are found first
+var exprOps = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+// ParseFieldExpr parse key<op>value, e.g. stream_id=abc, size>=1200, rtt<1.5s, error~timeout
+// Numbers and durations are compared by value, other values as text
+func ParseFieldExpr(s string) (FieldExpr, error) {
+	for i := 1; i < len(s); i++ {
+		for _, op := range exprOps {
+			if !strings.HasPrefix(s[i:], op) {
+				continue
+			}
+			x := FieldExpr{Key: s[:i], Op: op, Value: s[i+len(op):]}
+			if op == "~" || op == "!~" {
+				re, err := regexp.Compile(x.Value)
+				if err != nil {
+					return x, err
+				}
+				x.regexp = re
+			}
+			return x, nil
+		}
+	}
+	return FieldExpr{}, fmt.Errorf("invalid field expression %q", s)
+}
+
+// String linter
+func (x FieldExpr) String() string {
+	return x.Key + x.Op + x.Value
+}
+
+// Match check the field of e, a missing field only match != and !~
+func (x *FieldExpr) Match(e *Entry) bool {
+	value, ok := e.Field(x.Key)
+	if !ok {
+		return x.Op == "!=" || x.Op == "!~"
+	}
+	text := fmt.Sprint(value)
+	switch x.Op {
+	case "=":
+		return text == x.Value
+	case "!=":
+		return text != x.Value
+	case "~":
+		return x.regexp.MatchString(text)
+	case "!~":
+		return !x.regexp.MatchString(text)
+	}
+
+	cmp, ok := compareValues(value, x.Value)
+	if !ok {
+		return false
+	}
+	switch x.Op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	}
+	return cmp <= 0
+}
+
+// compareValues compare value with want as numbers or durations
+func compareValues(value interface{}, want string) (int, bool) {
+	var a, b float64
+	if d, ok := value.(time.Duration); ok {
+		w, err := time.ParseDuration(want)
+		if err != nil {
+			return 0, false
+		}
+		a, b = float64(d), float64(w)
+	} else {
+		text := fmt.Sprint(value)
+		x, err1 := strconv.ParseFloat(text, 64)
+		y, err2 := strconv.ParseFloat(want, 64)
+		if err1 != nil || err2 != nil {
+			// durations decoded as text
+			d, err1 := time.ParseDuration(text)
+			w, err2 := time.ParseDuration(want)
+			if err1 != nil || err2 != nil {
+				return 0, false
+			}
+			x, y = float64(d), float64(w)
+		}
+		a, b = x, y
+	}
+	switch {
+	case a < b:
+		return -1, true
+	case a > b:
+		return 1, true
+	}
+	return 0, true
+}
@@ -0,0 +1,372 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ForwardMode is the event mode of the Fluent Forward protocol
+type ForwardMode int
+
+const (
+	// ForwardMessage send each entry as [tag, time, record, option]
+	ForwardMessage ForwardMode = iota
+	// ForwardForward send batches as [tag, [[time, record], ...], option]
+	ForwardForward
+	// ForwardPacked send batches as [tag, bin of concatenated [time, record], option]
+	ForwardPacked
+)
+
+// ParseForwardMode return mode of a name: message, forward or packed
+func ParseForwardMode(s string) (ForwardMode, error) {
+	switch s {
+	case "message":
+		return ForwardMessage, nil
+	case "forward":
+		return ForwardForward, nil
+	case "packed":
+		return ForwardPacked, nil
+	}
+	return ForwardMessage, fmt.Errorf("unknown forward mode %q", s)
+}
+
+// FluentConfig config a FluentSink
+type FluentConfig struct {
+	Addr      string        // forward input of fluentd or fluent-bit, host:port
+	Tag       string        // tag of every event
+	Mode      ForwardMode   // event mode
+	Ack       bool          // ask the server to ack every message with the chunk option
+	BatchSize int           // max entries of a Forward or PackedForward message, default 100
+	QueueSize int           // max entries waiting for the sender, default 1000
+	Interval  time.Duration // send a pending batch every Interval, default 1s
+	Timeout   time.Duration // dial, write and ack timeout, default 5s
+	Clock     Clock         // default SystemClock
+	OnError   func(error)   // called when sending starts failing, default print on stderr
+}
+
+// ErrFluentQueueFull is returned by WriteEntry when the entry is dropped
+var ErrFluentQueueFull = errors.New("fluent queue is full")
+
+// FluentSink is a Log sending entries as msgpack events with the Fluent Forward protocol.
+// Records have a level, a message and a key per field, time is an EventTime.
+// Entries are queued and sent by a goroutine, so logging never waits for the
+// network. The connection is opened on the first send and again after an
+// error. A batch is kept until it is sent (and acked) and retried every
+// Interval, meanwhile the queue fills up and new entries are dropped and counted.
+type FluentSink struct {
+	leveled
+	*stackCounter
+	cfg      FluentConfig
+	queue    chan []byte     // encoded [time, record] events
+	flushes  chan chan error // Flush requests
+	dropped  int64           // atomic, entries dropped on a full queue or at close
+	done     chan struct{}
+	closed   chan struct{} // serve returned
+	doneOnce sync.Once
+	err      error // last send error at close
+
+	// owned by serve
+	conn    net.Conn
+	reader  *bufio.Reader
+	events  [][]byte // events waiting to be sent
+	failing bool     // the last send failed, errors are reported once
+}
+
+// NewFluentSink return a sink sending to cfg.Addr
+func NewFluentSink(cfg FluentConfig) *FluentSink {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = reportWriteError
+	}
+	f := &FluentSink{
+		cfg:     cfg,
+		queue:   make(chan []byte, cfg.QueueSize),
+		flushes: make(chan chan error),
+		done:    make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	f.leveled = f.log
+	f.stackCounter = newStackCounter(cfg.Clock, f.WriteEntry)
+	go f.serve()
+	return f
+}
+
+func (f *FluentSink) log(level Level, v ...interface{}) {
+	e := NewEntry(level, v...)
+	e.Time = f.cfg.Clock.Now()
+	f.WriteEntry(e)
+}
+
+// WriteEntry queue e, it is dropped if the queue is full
+func (f *FluentSink) WriteEntry(e *Entry) error {
+	select {
+	case <-f.done:
+		atomic.AddInt64(&f.dropped, 1)
+		return os.ErrClosed
+	default:
+	}
+	select {
+	case f.queue <- appendFluentEvent(nil, e):
+		return nil
+	default:
+		atomic.AddInt64(&f.dropped, 1)
+		return ErrFluentQueueFull
+	}
+}
+
+// Dropped return the number of entries dropped because the queue was full
+// or the sink closed before they were sent
+func (f *FluentSink) Dropped() int64 {
+	return atomic.LoadInt64(&f.dropped)
+}
+
+// Flush send the queued entries and return the send error
+func (f *FluentSink) Flush() error {
+	reply := make(chan error, 1)
+	select {
+	case f.flushes <- reply:
+		return <-reply
+	case <-f.closed:
+		return os.ErrClosed
+	}
+}
+
+// Sync send pending stack counters and queued entries
+func (f *FluentSink) Sync() error {
+	f.dumpStacks()
+	return f.Flush()
+}
+
+// serve move queued entries into the batch and send it when full, every
+// interval and on Flush. The queue is not read while a full batch is unsent.
+func (f *FluentSink) serve() {
+	defer close(f.closed)
+	ticker := f.cfg.Clock.NewTicker(f.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		queue := f.queue
+		if len(f.events) >= f.cfg.BatchSize {
+			queue = nil
+		}
+		select {
+		case event := <-queue:
+			f.events = append(f.events, event)
+			if f.cfg.Mode == ForwardMessage || len(f.events) >= f.cfg.BatchSize {
+				f.flush()
+			}
+		case <-ticker.C():
+			f.flush()
+		case reply := <-f.flushes:
+			reply <- f.flushAll()
+		case <-f.done:
+			f.err = f.flushAll()
+			if n := len(f.events) + len(f.queue); n > 0 {
+				atomic.AddInt64(&f.dropped, int64(n))
+			}
+			if f.conn != nil {
+				f.conn.Close()
+				f.conn = nil
+			}
+			return
+		}
+	}
+}
+
+// flushAll send the batch and the queued entries
+func (f *FluentSink) flushAll() error {
+	for {
+		for len(f.events) < f.cfg.BatchSize && len(f.queue) > 0 {
+			f.events = append(f.events, <-f.queue)
+		}
+		if len(f.events) == 0 {
+			return nil
+		}
+		if err := f.flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// flush send the batch, events are removed once sent.
+// The first error is reported until a send succeeds again.
+func (f *FluentSink) flush() error {
+	err := f.sendEvents()
+	if err != nil && !f.failing {
+		f.cfg.OnError(fmt.Errorf("fluent %s: %w", f.cfg.Addr, err))
+	}
+	f.failing = err != nil
+	return err
+}
+
+func (f *FluentSink) sendEvents() error {
+	if f.cfg.Mode == ForwardMessage {
+		for len(f.events) > 0 {
+			chunk := f.chunk()
+			n := 3
+			if chunk != "" {
+				n++
+			}
+			msg := appendMsgpackArray(nil, n)
+			msg = appendMsgpackString(msg, f.cfg.Tag)
+			msg = append(msg, f.events[0]...)
+			if chunk != "" {
+				msg = appendFluentOption(msg, -1, chunk)
+			}
+			if err := f.send(msg, chunk); err != nil {
+				return err
+			}
+			f.events = f.events[1:]
+		}
+		f.events = nil
+		return nil
+	}
+
+	if len(f.events) == 0 {
+		return nil
+	}
+	var batch []byte
+	for _, event := range f.events {
+		batch = appendMsgpackArray(batch, 2)
+		batch = append(batch, event...)
+	}
+	chunk := f.chunk()
+	msg := appendMsgpackArray(nil, 3)
+	msg = appendMsgpackString(msg, f.cfg.Tag)
+	if f.cfg.Mode == ForwardPacked {
+		msg = appendMsgpackBin(msg, batch)
+	} else {
+		msg = appendMsgpackArray(msg, len(f.events))
+		msg = append(msg, batch...)
+	}
+	msg = appendFluentOption(msg, len(f.events), chunk)
+	if err := f.send(msg, chunk); err != nil {
+		return err
+	}
+	f.events = f.events[:0]
+	return nil
+}
+
+// chunk return a new chunk id if ack is enabled
+func (f *FluentSink) chunk() string {
+	if !f.cfg.Ack {
+		return ""
+	}
+	var id [16]byte
+	rand.Read(id[:])
+	return base64.StdEncoding.EncodeToString(id[:])
+}
+
+// appendFluentEvent append time and record of e
+func appendFluentEvent(buf []byte, e *Entry) []byte {
+	buf = appendMsgpackEventTime(buf, e.Time)
+	buf = appendMsgpackMap(buf, 2+len(e.Fields))
+	buf = appendMsgpackString(buf, "level")
+	buf = appendMsgpackString(buf, e.Level.String())
+	buf = appendMsgpackString(buf, "message")
+	buf = appendMsgpackString(buf, e.Message)
+	for _, field := range e.Fields {
+		buf = appendMsgpackString(buf, field.Key)
+		buf = appendMsgpackField(buf, field)
+	}
+	return buf
+}
+
+// appendFluentOption append the option map, size is omitted if negative
+func appendFluentOption(buf []byte, size int, chunk string) []byte {
+	n := 0
+	if size >= 0 {
+		n++
+	}
+	if chunk != "" {
+		n++
+	}
+	buf = appendMsgpackMap(buf, n)
+	if size >= 0 {
+		buf = appendMsgpackString(buf, "size")
+		buf = appendMsgpackInt(buf, int64(size))
+	}
+	if chunk != "" {
+		buf = appendMsgpackString(buf, "chunk")
+		buf = appendMsgpackString(buf, chunk)
+	}
+	return buf
+}
+
+// send write msg and wait its ack, retry once on a new connection
+func (f *FluentSink) send(msg []byte, chunk string) error {
+	err := f.sendOnce(msg, chunk)
+	if err != nil {
+		err = f.sendOnce(msg, chunk)
+	}
+	return err
+}
+
+func (f *FluentSink) sendOnce(msg []byte, chunk string) error {
+	if f.conn == nil {
+		conn, err := net.DialTimeout("tcp", f.cfg.Addr, f.cfg.Timeout)
+		if err != nil {
+			return err
+		}
+		f.conn = conn
+		f.reader = bufio.NewReader(conn)
+	}
+	err := f.exchange(msg, chunk)
+	if err != nil {
+		f.conn.Close()
+		f.conn = nil
+	}
+	return err
+}
+
+func (f *FluentSink) exchange(msg []byte, chunk string) error {
+	f.conn.SetWriteDeadline(time.Now().Add(f.cfg.Timeout))
+	if _, err := f.conn.Write(msg); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+
+	f.conn.SetReadDeadline(time.Now().Add(f.cfg.Timeout))
+	resp, err := readMsgpack(f.reader)
+	if err != nil {
+		return fmt.Errorf("fluent ack: %w", err)
+	}
+	if m, ok := resp.(map[string]interface{}); !ok || m["ack"] != chunk {
+		return fmt.Errorf("fluent ack: unexpected response %v", resp)
+	}
+	return nil
+}
+
+// Close send pending stack counters and queued entries then close the
+// connection, entries not sent are dropped
+func (f *FluentSink) Close() error {
+	f.Stop()
+	f.doneOnce.Do(func() {
+		close(f.done)
+	})
+	<-f.closed
+	return f.err
+}
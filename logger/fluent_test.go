@@ -0,0 +1,256 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fluentEvent is a decoded event
+type fluentEvent struct {
+	tag    string
+	time   time.Time
+	record map[string]interface{}
+}
+
+// fluentServer decode forward messages into events and ack chunks
+func fluentServer(t *testing.T) (string, <-chan fluentEvent, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan fluentEvent, 100)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveFluent(t, conn, events)
+		}
+	}()
+	return ln.Addr().String(), events, func() { ln.Close() }
+}
+
+func serveFluent(t *testing.T, conn net.Conn, events chan<- fluentEvent) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		v, err := readMsgpack(r)
+		if err != nil {
+			return
+		}
+		msg := v.([]interface{})
+		tag := msg[0].(string)
+		var option map[string]interface{}
+
+		switch entries := msg[1].(type) {
+		case msgpackExt: // message
+			events <- decodeFluentEvent(tag, entries, msg[2])
+			if len(msg) > 3 {
+				option = msg[3].(map[string]interface{})
+			}
+		case []interface{}: // forward
+			for _, entry := range entries {
+				pair := entry.([]interface{})
+				events <- decodeFluentEvent(tag, pair[0].(msgpackExt), pair[1])
+			}
+			option = msg[2].(map[string]interface{})
+		case []byte: // packed forward
+			packed := bytes.NewReader(entries)
+			for packed.Len() > 0 {
+				entry, err := readMsgpack(packed)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				pair := entry.([]interface{})
+				events <- decodeFluentEvent(tag, pair[0].(msgpackExt), pair[1])
+			}
+			option = msg[2].(map[string]interface{})
+		}
+
+		if chunk, ok := option["chunk"].(string); ok {
+			conn.Write(appendMsgpackString(appendMsgpackString(appendMsgpackMap(nil, 1), "ack"), chunk))
+		}
+	}
+}
+
+func decodeFluentEvent(tag string, t msgpackExt, record interface{}) fluentEvent {
+	sec := binary.BigEndian.Uint32(t.Data[:4])
+	nsec := binary.BigEndian.Uint32(t.Data[4:])
+	return fluentEvent{tag, time.Unix(int64(sec), int64(nsec)), record.(map[string]interface{})}
+}
+
+func receiveFluent(t *testing.T, events <-chan fluentEvent) fluentEvent {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no fluent event")
+	}
+	return fluentEvent{}
+}
+
+func TestFluentSink(t *testing.T) {
+	addr, events, stop := fluentServer(t)
+	defer stop()
+
+	now := time.Unix(1599701415, 123456789)
+	for _, mode := range []ForwardMode{ForwardMessage, ForwardForward, ForwardPacked} {
+		for _, ack := range []bool{false, true} {
+			f := NewFluentSink(FluentConfig{
+				Addr:      addr,
+				Tag:       "gologs.test",
+				Mode:      mode,
+				Ack:       ack,
+				BatchSize: 2,
+				Interval:  time.Hour,
+				Clock:     NewManualClock(now),
+			})
+			f.WARN("stream closed", String("stream_id", "abc"), Int("size", -1200), Any("ok", true))
+			f.INFO("second")
+			f.DEBUG("pending")
+			if err := f.Close(); err != nil {
+				t.Fatalf("mode %d ack %v: %v", mode, ack, err)
+			}
+
+			e := receiveFluent(t, events)
+			if e.tag != "gologs.test" || !e.time.Equal(now) {
+				t.Errorf("mode %d: tag %q time %v", mode, e.tag, e.time)
+			}
+			r := e.record
+			if r["level"] != "WARN" || r["message"] != "stream closed" || r["stream_id"] != "abc" || r["size"] != int64(-1200) || r["ok"] != true {
+				t.Errorf("mode %d: record %v", mode, r)
+			}
+			if e = receiveFluent(t, events); e.record["message"] != "second" {
+				t.Errorf("mode %d: second record %v", mode, e.record)
+			}
+			if e = receiveFluent(t, events); e.record["message"] != "pending" {
+				t.Errorf("mode %d: pending record %v", mode, e.record)
+			}
+		}
+	}
+}
+
+func TestFluentAckTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// accept and never ack
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	var reported []error
+	f := NewFluentSink(FluentConfig{Addr: ln.Addr().String(), Ack: true, Timeout: 50 * time.Millisecond, Interval: time.Hour,
+		OnError: func(err error) { reported = append(reported, err) }})
+	defer f.Close()
+
+	start := time.Now()
+	if err := f.WriteEntry(NewEntry(ErrorLevel, "lost")); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 25*time.Millisecond {
+		t.Error("WriteEntry should not wait for the network")
+	}
+	if err := f.Flush(); err == nil {
+		t.Error("expected ack timeout")
+	}
+	f.Flush()
+	if len(reported) != 1 {
+		t.Errorf("expect the error reported once, got %v", reported)
+	}
+}
+
+func TestFluentKeepBatch(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	f := NewFluentSink(FluentConfig{Addr: addr, Mode: ForwardForward, BatchSize: 2, QueueSize: 2, Interval: time.Hour,
+		OnError: func(error) {}})
+	defer f.Close()
+	// entries wait in the unsent batch and the queue until it is full
+	n := 0
+	for ; n < 100 && f.Dropped() == 0; n++ {
+		f.INFO(strconv.Itoa(n))
+		time.Sleep(time.Millisecond)
+	}
+	if f.Dropped() != 1 {
+		t.Fatalf("dropped %d entries, want 1", f.Dropped())
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip("address reused:", err)
+	}
+	defer ln.Close()
+	events := make(chan fluentEvent, 100)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			serveFluent(t, conn, events)
+		}
+	}()
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n-1; i++ {
+		if e := receiveFluent(t, events); e.record["message"] != strconv.Itoa(i) {
+			t.Fatalf("got %v, want %d", e.record, i)
+		}
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	values := []interface{}{int64(5), int64(-5), int64(-200), int64(-40000), int64(-3000000000), uint64(1 << 63), 1.5, "", "long string over thirty one chars", true, nil}
+	buf := appendMsgpackArray(nil, len(values))
+	for _, v := range values {
+		buf = appendMsgpackField(buf, Any("", v))
+	}
+	got, err := readMsgpack(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range got.([]interface{}) {
+		if v != values[i] {
+			t.Errorf("value %d = %#v, want %#v", i, v, values[i])
+		}
+	}
+}
+
+func TestMsgpackLimits(t *testing.T) {
+	// an array32 header announcing 2^32-1 elements is rejected before allocating
+	huge := []byte{0xdd, 0xff, 0xff, 0xff, 0xff}
+	if _, err := readMsgpack(bytes.NewReader(huge)); !errors.Is(err, errMsgpackFormat) {
+		t.Errorf("huge array: got %v", err)
+	}
+
+	var nested []byte
+	for i := 0; i <= maxMsgpackDepth; i++ {
+		nested = appendMsgpackArray(nested, 1)
+	}
+	nested = appendMsgpackNil(nested)
+	if _, err := readMsgpack(bytes.NewReader(nested)); !errors.Is(err, errMsgpackFormat) {
+		t.Errorf("nested arrays: got %v", err)
+	}
+	if _, err := readMsgpack(bytes.NewReader(nested[1:])); err != nil {
+		t.Errorf("arrays nested %d levels: %v", maxMsgpackDepth, err)
+	}
+}
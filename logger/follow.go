@@ -0,0 +1,167 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"time"
+)
+
+// Follower read lines of a file like tail -F: path is opened again when the
+// file is rotated (renamed or removed then created again) and read from the
+// start when it is truncated. Only complete lines are returned.
+type Follower struct {
+	path    string
+	poll    time.Duration
+	file    *os.File
+	info    os.FileInfo // of the open file
+	reader  *bufio.Reader
+	offset  int64  // after the last returned line
+	partial []byte // incomplete line read so far
+}
+
+// NewFollower return a follower of path starting at offset, the file is
+// checked for new lines every poll
+func NewFollower(path string, offset int64, poll time.Duration) *Follower {
+	return &Follower{
+		path:   path,
+		poll:   poll,
+		offset: offset,
+	}
+}
+
+// Path linter
+func (f *Follower) Path() string {
+	return f.path
+}
+
+// Offset return the position after the last returned line in the open file
+func (f *Follower) Offset() int64 {
+	return f.offset
+}
+
+// Info return the open file info, nil until it is opened
+func (f *Follower) Info() os.FileInfo {
+	return f.info
+}
+
+// Next return the next line without its line ending, waiting for it.
+// It returns io.EOF once stop is closed.
+func (f *Follower) Next(stop <-chan struct{}) ([]byte, error) {
+	for {
+		if f.file == nil {
+			if err := f.open(); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		if f.file != nil {
+			line, err := f.readLine()
+			if line != nil || err != nil {
+				return line, err
+			}
+		}
+
+		select {
+		case <-stop:
+			return nil, io.EOF
+		case <-time.After(f.poll):
+		}
+	}
+}
+
+// statFollowed is os.Stat, replaced by tests
+var statFollowed = os.Stat
+
+// readLine return a complete line, nil if none is available yet
+func (f *Follower) readLine() ([]byte, error) {
+	if line, err := f.read(); line != nil || err != nil {
+		return line, err
+	}
+
+	// nothing more to read, check if the file was rotated or truncated
+	info, err := statFollowed(f.path)
+	switch {
+	case err != nil && !os.IsNotExist(err):
+		return nil, err
+	case err != nil || !os.SameFile(info, f.info):
+		// lines may be written between the last read and the rotation,
+		// the old file is read to its end before it is closed
+		if line, err := f.read(); line != nil || err != nil {
+			return line, err
+		}
+		// the rotated file is complete, return its last line without a newline
+		line := f.partial
+		f.Close()
+		f.offset = 0
+		if len(line) > 0 {
+			return line, nil
+		}
+		return nil, nil
+	case info.Size() < f.offset+int64(len(f.partial)):
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		f.reader.Reset(f.file)
+		f.offset = 0
+		f.partial = nil
+		return f.readLine()
+	}
+	return nil, nil
+}
+
+// read return the next complete line of the open file, nil at its end
+func (f *Follower) read() ([]byte, error) {
+	for {
+		chunk, err := f.reader.ReadSlice('\n')
+		f.partial = append(f.partial, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == nil {
+			line := f.partial
+			f.offset += int64(len(line))
+			f.partial = nil
+			return bytes.TrimRight(line, "\r\n"), nil
+		}
+		if err != io.EOF {
+			return nil, err
+		}
+		return nil, nil
+	}
+}
+
+// open path at offset, from the start if it is shorter
+func (f *Follower) open() error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if info.Size() < f.offset {
+		f.offset = 0
+	}
+	if _, err := file.Seek(f.offset, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.info = info
+	f.reader = bufio.NewReaderSize(file, 64*1024)
+	return nil
+}
+
+// Close the open file, Next open it again
+func (f *Follower) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	f.partial = nil
+	return err
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFollowerRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	ioutil.WriteFile(path, []byte("one\ntwo"), 0644)
+
+	stop := make(chan struct{})
+	f := NewFollower(path, 0, time.Millisecond)
+	defer f.Close()
+	next := func(want string) {
+		t.Helper()
+		line, err := f.Next(stop)
+		if err != nil || string(line) != want {
+			t.Fatalf("got %q %v, want %q", line, err, want)
+		}
+	}
+	next("one")
+
+	// the partial line is completed before rotation
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString(" half\nthree")
+	file.Close()
+	next("two half")
+
+	os.Rename(path, path+".1")
+	ioutil.WriteFile(path, []byte("four\n"), 0644)
+	next("three")
+	next("four")
+
+	// truncated and written again
+	ioutil.WriteFile(path, []byte("5\n"), 0644)
+	next("5")
+	if f.Offset() != 2 {
+		t.Errorf("offset %d", f.Offset())
+	}
+
+	close(stop)
+	if _, err := f.Next(stop); err == nil {
+		t.Error("expected io.EOF after stop")
+	}
+}
+
+func TestFollowerRotateAfterEOF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	ioutil.WriteFile(path, []byte("one\n"), 0644)
+
+	// a line is appended then the file rotated after the end was read
+	// and before the follower checks the path
+	defer func() { statFollowed = os.Stat }()
+	statFollowed = func(name string) (os.FileInfo, error) {
+		statFollowed = os.Stat
+		file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		file.WriteString("two\n")
+		file.Close()
+		os.Rename(path, path+".1")
+		ioutil.WriteFile(path, []byte("three\n"), 0644)
+		return os.Stat(name)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	f := NewFollower(path, 0, time.Millisecond)
+	defer f.Close()
+	for _, want := range []string{"one", "two", "three"} {
+		line, err := f.Next(stop)
+		if err != nil || string(line) != want {
+			t.Fatalf("got %q %v, want %q", line, err, want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HookFunc is called with entries of the levels it was added for.
+// Fields appended to e are written with the entry if the hook returns in time.
+type HookFunc func(e *Entry) error
+
+type hook struct {
+	levels map[Level]bool // empty for every level
+	fn     HookFunc
+}
+
+func (h *hook) match(level Level) bool {
+	return len(h.levels) == 0 || h.levels[level]
+}
+
+// DefaultMaxAbandoned is the number of late hooks left running before
+// hooks are skipped
+const DefaultMaxAbandoned = 100
+
+// Hooks is a set of hooks sharing a timeout
+type Hooks struct {
+	hooks        []*hook
+	timeout      time.Duration
+	maxAbandoned int64
+	abandoned    int64 // atomic, late hooks still running
+	mutex        sync.RWMutex
+}
+
+// NewHooks return an empty set, the hooks of an entry may run for timeout
+func NewHooks(timeout time.Duration) *Hooks {
+	return &Hooks{
+		timeout:      timeout,
+		maxAbandoned: DefaultMaxAbandoned,
+	}
+}
+
+// SetMaxAbandoned set the number of late hooks left running before new
+// ones are skipped
+func (h *Hooks) SetMaxAbandoned(n int) {
+	atomic.StoreInt64(&h.maxAbandoned, int64(n))
+}
+
+// Abandoned return the number of late hooks still running
+func (h *Hooks) Abandoned() int {
+	return int(atomic.LoadInt64(&h.abandoned))
+}
+
+// Add register fn for levels, no level means every level
+// The returned func remove the hook
+func (h *Hooks) Add(levels []Level, fn HookFunc) func() {
+	hk := &hook{
+		levels: make(map[Level]bool),
+		fn:     fn,
+	}
+	for _, level := range levels {
+		hk.levels[level] = true
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.hooks = append(h.hooks, hk)
+
+	return func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		for i, item := range h.hooks {
+			if item == hk {
+				h.hooks = append(h.hooks[:i:i], h.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Len return number of hooks
+func (h *Hooks) Len() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.hooks)
+}
+
+func (h *Hooks) matching(level Level) []*hook {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	var result []*hook
+	for _, hk := range h.hooks {
+		if hk.match(level) {
+			result = append(result, hk)
+		}
+	}
+	return result
+}
+
+// hookResult is the outcome of a hook of Fire
+type hookResult struct {
+	index int
+	entry *Entry
+	err   error
+}
+
+// hookCall is the state of hooks fired for an entry
+type hookCall struct {
+	results chan hookResult
+	expired bool // the deadline passed, late hooks are abandoned
+	mutex   sync.Mutex
+}
+
+// Fire run matching hooks together and return the entry with fields they attached,
+// in the order of the hooks. Hooks still running after timeout are left behind,
+// reported as errors and counted until they return.
+func (h *Hooks) Fire(e *Entry) (*Entry, []error) {
+	matching := h.matching(e.Level)
+	if len(matching) == 0 {
+		return e, nil
+	}
+
+	errs := make([]error, len(matching))
+	call := &hookCall{results: make(chan hookResult, len(matching))}
+	started := 0
+	for i, hk := range matching {
+		if n := atomic.LoadInt64(&h.abandoned); n >= atomic.LoadInt64(&h.maxAbandoned) {
+			errs[i] = fmt.Errorf("hook skipped, %d late hooks still running", n)
+			continue
+		}
+		// each hook get its own copy, a late hook can not race with the write
+		clone := *e
+		clone.Fields = append([]Field(nil), e.Fields...)
+		started++
+		go h.run(call, i, hk.fn, &clone)
+	}
+
+	entries := make([]*Entry, len(matching))
+	timer := time.NewTimer(h.timeout)
+	defer timer.Stop()
+	for received := 0; received < started; {
+		select {
+		case r := <-call.results:
+			entries[r.index], errs[r.index] = r.entry, r.err
+			received++
+		case <-timer.C:
+			call.mutex.Lock()
+			call.expired = true
+			for len(call.results) > 0 {
+				r := <-call.results
+				entries[r.index], errs[r.index] = r.entry, r.err
+				received++
+			}
+			atomic.AddInt64(&h.abandoned, int64(started-received))
+			call.mutex.Unlock()
+			for i := range matching {
+				if entries[i] == nil && errs[i] == nil {
+					errs[i] = fmt.Errorf("hook timeout after %v", h.timeout)
+				}
+			}
+			received = started
+		}
+	}
+
+	result := e
+	for i, entry := range entries {
+		if entry == nil || errs[i] != nil || len(entry.Fields) == len(e.Fields) {
+			continue
+		}
+		if result == e {
+			clone := *e
+			clone.Fields = append([]Field(nil), e.Fields...)
+			result = &clone
+		}
+		result.Fields = append(result.Fields, entry.Fields[len(e.Fields):]...)
+	}
+
+	var reported []error
+	for _, err := range errs {
+		if err != nil {
+			reported = append(reported, err)
+		}
+	}
+	return result, reported
+}
+
+// run a hook of call, its result is dropped if it returns after the deadline
+func (h *Hooks) run(call *hookCall, index int, fn HookFunc, e *Entry) {
+	r := hookResult{index: index, entry: e}
+	defer func() {
+		if p := recover(); p != nil {
+			r.err = fmt.Errorf("hook panic: %v", p)
+		}
+		call.mutex.Lock()
+		defer call.mutex.Unlock()
+		if call.expired {
+			atomic.AddInt64(&h.abandoned, -1)
+			return
+		}
+		call.results <- r
+	}()
+	r.err = fn(e)
+}
+
+// HookLog run hooks on entries before writing them to next
+type HookLog struct {
+	leveled
+	next  Log
+	hooks *Hooks
+}
+
+// NewHookLog return a log firing hooks in front of next
+func NewHookLog(next Log, hooks *Hooks) *HookLog {
+	h := &HookLog{
+		next:  next,
+		hooks: hooks,
+	}
+	h.leveled = h.log
+	return h
+}
+
+// STACK linter
+func (h *HookLog) STACK(v ...string) {
+	h.next.STACK(v...)
+}
+
+// WriteEntry fire hooks then write e
+func (h *HookLog) WriteEntry(e *Entry) error {
+	e = h.fire(e)
+	return WriteEntry(h.next, e)
+}
+
+// LogEntry fire hooks then log e
+func (h *HookLog) LogEntry(e *Entry) {
+	if h.hooks.Len() > 0 {
+		e = h.fire(e)
+	}
+	LogEntry(h.next, e)
+}
+
+func (h *HookLog) log(level Level, v ...interface{}) {
+	if h.hooks.Len() == 0 {
+		Emit(h.next, level, v...)
+		return
+	}
+	e := h.fire(NewEntry(level, v...))
+	Emit(h.next, level, e.Args()...)
+}
+
+// fire run hooks and report their errors to next
+func (h *HookLog) fire(e *Entry) *Entry {
+	e, errs := h.hooks.Fire(e)
+	for _, err := range errs {
+		h.next.WARN(fmt.Sprintf("log hook error: %v", err))
+	}
+	return e
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	hooks := NewHooks(time.Second)
+	l := NewHookLog(newTestLog(buf), hooks)
+
+	var errorCount int32
+	hooks.Add([]Level{ErrorLevel}, func(e *Entry) error {
+		atomic.AddInt32(&errorCount, 1)
+		return nil
+	})
+	remove := hooks.Add(nil, func(e *Entry) error {
+		e.Fields = append(e.Fields, Any("host", "node-1"))
+		return nil
+	})
+
+	l.INFO("added")
+	l.ERROR("failed")
+	remove()
+	l.INFO("removed")
+
+	want := "INFO added host=node-1\nERROR failed host=node-1\nINFO removed\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+	if atomic.LoadInt32(&errorCount) != 1 {
+		t.Fatalf("expect 1 error, got %d", errorCount)
+	}
+}
+
+func TestHookTimeoutAndError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	hooks := NewHooks(20 * time.Millisecond)
+	l := NewHookLog(newTestLog(buf), hooks)
+
+	release := make(chan struct{})
+	defer close(release)
+	hooks.Add([]Level{WarnLevel}, func(e *Entry) error {
+		e.Fields = append(e.Fields, Any("late", true))
+		<-release
+		return nil
+	})
+	hooks.Add([]Level{WarnLevel}, func(e *Entry) error {
+		return errors.New("webhook down")
+	})
+
+	start := time.Now()
+	l.WARN("slow")
+	if time.Since(start) > time.Second {
+		t.Fatal("slow hook should not stall the log")
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "log hook error: hook timeout") ||
+		!strings.Contains(out, "log hook error: webhook down") ||
+		!strings.Contains(out, "WARN slow\n") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestHooksDeadline(t *testing.T) {
+	buf := &bytes.Buffer{}
+	hooks := NewHooks(50 * time.Millisecond)
+	hooks.SetMaxAbandoned(3)
+	l := NewHookLog(newTestLog(buf), hooks)
+
+	release := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		hooks.Add(nil, func(e *Entry) error {
+			<-release
+			return nil
+		})
+	}
+	hooks.Add(nil, func(e *Entry) error {
+		e.Fields = append(e.Fields, Any("host", "node-1"))
+		return nil
+	})
+
+	start := time.Now()
+	l.INFO("first")
+	if d := time.Since(start); d > 150*time.Millisecond {
+		t.Fatalf("hooks should share one deadline, took %v", d)
+	}
+	if n := hooks.Abandoned(); n != 3 {
+		t.Fatalf("expect 3 abandoned hooks, got %d", n)
+	}
+
+	l.INFO("second")
+	if !strings.Contains(buf.String(), "INFO first host=node-1\n") || !strings.Contains(buf.String(), "INFO second\n") ||
+		strings.Count(buf.String(), "hook skipped, 3 late hooks still running") != 4 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	close(release)
+	for i := 0; i < 100 && hooks.Abandoned() > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := hooks.Abandoned(); n != 0 {
+		t.Fatalf("late hooks should be counted until they return, got %d", n)
+	}
+}
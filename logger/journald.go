@@ -0,0 +1,188 @@
+//go:build linux
+// +build linux
+
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// JournalSocket is the journald native protocol socket
+const JournalSocket = "/run/systemd/journal/socket"
+
+// JournalSink is a Log sending entries to journald with the native protocol.
+// Levels are sent as PRIORITY, fields as upper case journal fields and
+// the caller field as CODE_FILE and CODE_LINE.
+// Entries too large for a datagram are sent as a file descriptor.
+type JournalSink struct {
+	leveled
+	*stackCounter
+	conn       *net.UnixConn
+	addr       *net.UnixAddr
+	identifier string
+}
+
+// NewJournalSink return a sink sending to the socket path, default JournalSocket.
+// identifier is the SYSLOG_IDENTIFIER of entries, can be empty
+// Only the WithClock option is used
+func NewJournalSink(path, identifier string, opts ...Option) (*JournalSink, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	if path == "" {
+		path = JournalSocket
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	// unbound socket, datagrams are sent to addr so a journald restart is not an issue
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	j := &JournalSink{
+		conn:       conn,
+		addr:       &net.UnixAddr{Name: path, Net: "unixgram"},
+		identifier: identifier,
+	}
+	j.leveled = j.log
+	j.stackCounter = newStackCounter(o.clock, j.WriteEntry)
+	return j, nil
+}
+
+func (j *JournalSink) log(level Level, v ...interface{}) {
+	j.WriteEntry(NewEntry(level, v...))
+}
+
+// WriteEntry send e to journald, time is set by journald when receiving it
+func (j *JournalSink) WriteEntry(e *Entry) error {
+	data := j.encode(e)
+	_, _, err := j.conn.WriteMsgUnix(data, nil, j.addr)
+	if err == nil || !isMsgSize(err) {
+		return err
+	}
+	return j.sendFile(data)
+}
+
+// isMsgSize return true if err is caused by a datagram too large
+func isMsgSize(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && (errno == syscall.EMSGSIZE || errno == syscall.ENOBUFS)
+}
+
+// sendFile write data into an unlinked temp file and send its descriptor
+func (j *JournalSink) sendFile(data []byte) error {
+	dir := "/dev/shm"
+	if _, err := os.Stat(dir); err != nil {
+		dir = os.TempDir()
+	}
+	f, err := ioutil.TempFile(dir, "journal.")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	_, _, err = j.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), j.addr)
+	return err
+}
+
+// encode return e as native protocol fields
+func (j *JournalSink) encode(e *Entry) []byte {
+	buf := make([]byte, 0, 256)
+	buf = appendJournalField(buf, "MESSAGE", e.Message)
+	buf = appendJournalField(buf, "PRIORITY", strconv.Itoa(journalPriority(e.Level)))
+	if j.identifier != "" {
+		buf = appendJournalField(buf, "SYSLOG_IDENTIFIER", j.identifier)
+	}
+	for _, field := range e.Fields {
+		value := string(appendTextValue(nil, field))
+		if field.Key == CallerKey {
+			if i := strings.LastIndexByte(value, ':'); i > 0 {
+				buf = appendJournalField(buf, "CODE_FILE", value[:i])
+				buf = appendJournalField(buf, "CODE_LINE", value[i+1:])
+				continue
+			}
+		}
+		if name := JournalFieldName(field.Key); name != "" {
+			buf = appendJournalField(buf, name, value)
+		}
+	}
+	return buf
+}
+
+// journalPriority return syslog priority of level
+func journalPriority(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 7
+	case InfoLevel:
+		return 6
+	case WarnLevel:
+		return 4
+	case PanicLevel, FatalLevel:
+		return 2
+	}
+	return 3
+}
+
+// appendJournalField append NAME=value, values with a newline are sent
+// as NAME, a little endian 64 bit length and the value
+func appendJournalField(buf []byte, name, value string) []byte {
+	buf = append(buf, name...)
+	if strings.IndexByte(value, '\n') < 0 {
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		return append(buf, '\n')
+	}
+	buf = append(buf, '\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	buf = append(buf, size[:]...)
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+// JournalFieldName return key as a journal field name: upper case letters,
+// digits and '_', not starting with '_' or a digit, at most 64 characters.
+// It return "" if nothing is left.
+func JournalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// Sync write pending stack counters, entries are sent unbuffered
+func (j *JournalSink) Sync() error {
+	j.dumpStacks()
+	return nil
+}
+
+// Close stop the sink and close its socket
+func (j *JournalSink) Close() error {
+	j.Stop()
+	return j.conn.Close()
+}
@@ -0,0 +1,37 @@
+//go:build !linux
+// +build !linux
+
+package logger
+
+import "errors"
+
+// JournalSocket is the journald native protocol socket
+const JournalSocket = "/run/systemd/journal/socket"
+
+var errJournalUnsupported = errors.New("journald is only supported on linux")
+
+// JournalSink is only available on linux
+type JournalSink struct {
+	leveled
+	*stackCounter
+}
+
+// NewJournalSink return an error, journald is only supported on linux
+func NewJournalSink(path, identifier string, opts ...Option) (*JournalSink, error) {
+	return nil, errJournalUnsupported
+}
+
+// WriteEntry linter
+func (j *JournalSink) WriteEntry(e *Entry) error {
+	return errJournalUnsupported
+}
+
+// Sync linter
+func (j *JournalSink) Sync() error {
+	return nil
+}
+
+// Close linter
+func (j *JournalSink) Close() error {
+	return nil
+}
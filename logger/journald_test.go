@@ -0,0 +1,132 @@
+//go:build linux
+// +build linux
+
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// parseJournal decode native protocol fields
+func parseJournal(t *testing.T, data []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "=\n")
+		if i < 0 {
+			t.Fatalf("bad field %q", data)
+		}
+		name := string(data[:i])
+		if data[i] == '=' {
+			end := bytes.IndexByte(data, '\n')
+			fields[name] = string(data[i+1 : end])
+			data = data[end+1:]
+			continue
+		}
+		size := binary.LittleEndian.Uint64(data[i+1 : i+9])
+		fields[name] = string(data[i+9 : i+9+int(size)])
+		data = data[i+9+int(size)+1:]
+	}
+	return fields
+}
+
+// readJournal read one datagram, or the file passed as descriptor
+func readJournal(t *testing.T, conn *net.UnixConn) map[string]string {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1<<16)
+	oob := make([]byte, 1024)
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oobn == 0 {
+		return parseJournal(t, buf[:n])
+	}
+
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	f.Seek(0, 0)
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseJournal(t, data)
+}
+
+func TestJournalSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "socket")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	j, err := NewJournalSink(path, "gologs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	j.WARN("stream\nclosed", String("stream_id", "abc"), Any("_boot", 1), String(CallerKey, "logs/fwd.go:42"))
+	got := readJournal(t, server)
+	want := map[string]string{
+		"MESSAGE":           "stream\nclosed",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "gologs",
+		"STREAM_ID":         "abc",
+		"BOOT":              "1",
+		"CODE_FILE":         "logs/fwd.go",
+		"CODE_LINE":         "42",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	// too large for a datagram, sent as a file descriptor
+	large := strings.Repeat("x", 1<<20)
+	if err := j.WriteEntry(&Entry{Level: DebugLevel, Message: large}); err != nil {
+		t.Fatal(err)
+	}
+	got = readJournal(t, server)
+	if got["MESSAGE"] != large || got["PRIORITY"] != "7" {
+		t.Errorf("large entry: priority %q, message of %d bytes", got["PRIORITY"], len(got["MESSAGE"]))
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	cases := map[string]string{
+		"stream_id": "STREAM_ID",
+		"_cursor":   "CURSOR",
+		"1x-y":      "X_Y",
+		"__":        "",
+	}
+	for key, want := range cases {
+		if got := JournalFieldName(key); got != want {
+			t.Errorf("JournalFieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
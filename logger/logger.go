@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"fmt"
 	"os"
+	"runtime"
 	"strconv"
 	"sync"
 	"time"
@@ -11,57 +13,184 @@ import (
 )
 
 // Log default method
+// PANIC and FATAL only write an entry of their level, the logs package
+// facade panics or exits after it
 type Log interface {
 	ERROR(v ...interface{})
 	INFO(v ...interface{})
 	WARN(v ...interface{})
 	DEBUG(v ...interface{})
+	PANIC(v ...interface{})
+	FATAL(v ...interface{})
 	STACK(v ...string)
 }
 
 // FactorLog custom log with factor pkg
 type FactorLog struct {
-	frmt   string         // format style log
-	stacks *AdvanceMap    // save for debug logs
-	log    *log.FactorLog // log
-	mutex  sync.RWMutex
+	frmt      string            // format style log
+	stacks    *AdvanceMap       // save for debug logs
+	out       *levelOutput      // log destination
+	formatter *log.StdFormatter // factorlog formatter
+	clock     Clock             // time of entries and stack ticker
+	utc       bool              // write time in UTC
+	multiline bool              // indent continuation lines instead of escaping newlines
+	fmtMutex  sync.Mutex        // formatter is not thread safe
+	mutex     sync.RWMutex
 }
 
 // NewFactorLog return new log with factor pkg
-func NewFactorLog() Log {
+// Color is only written to a terminal unless WithColor or NO_COLOR/FORCE_COLOR say otherwise
+func NewFactorLog(opts ...Option) Log {
 	// ftm2 := `%{Color "magenta"}[%{Date}] [%{Time}] %{Color "cyan"}[%{FullFunction}:%{Line}]  %{Color "yellow"}[%{SEVERITY}] %{Color "reset"}[%{Message}]`
 	// frmt := `%{Color "red" "ERROR"}%{Color "yellow" "WARN"}%{Color "green" "INFO"}%{Color "cyan" "DEBUG"}%{Color "blue" "STACK"}[%{Date} %{Time}] [%{SEVERITY}:%{File}:%{Line}] %{Message}%{Color "reset"}`
 
-	frmt := `%{Color "red" "ERROR"}%{Color "yellow" "WARN"}%{Color "green" "INFO"}%{Color "cyan" "DEBUG"}%{Color "blue" "STACK"} [%{Date}] [%{Time "15:04:05.000000000"}] [%{SEVERITY}] [%{Message}%{Color "reset"}]`
-	log := log.New(os.Stdout, log.NewStdFormatter(frmt))
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
 
+	frmt := o.build()
 	f := &FactorLog{
-		frmt:   frmt,
-		log:    log,
-		stacks: NewAdvanceMap(),
+		frmt:      frmt,
+		out:       newLevelOutput(o.out, o),
+		formatter: log.NewStdFormatter(frmt),
+		clock:     o.clock,
+		utc:       o.utc,
+		multiline: o.multi,
+		stacks:    NewAdvanceMap(),
 	}
-	go f.serve()
+	NewSupervisor(f, time.Second, time.Minute).Supervise("factorlog stacks", f.serve)
 	return f
 }
 
 // DEBUG linter auto println
 func (l *FactorLog) DEBUG(v ...interface{}) {
-	l.log.Debugln(v...)
+	l.output(DebugLevel, textArgs(v))
 }
 
 // ERROR linter auto println
 func (l *FactorLog) ERROR(v ...interface{}) {
-	l.log.Errorln(v...)
+	l.output(ErrorLevel, textArgs(v))
 }
 
 // INFO linter auto println
 func (l *FactorLog) INFO(v ...interface{}) {
-	l.log.Infoln(v...)
+	l.output(InfoLevel, textArgs(v))
 }
 
 // WARN linter auto println
 func (l *FactorLog) WARN(v ...interface{}) {
-	l.log.Warnln(v...)
+	l.output(WarnLevel, textArgs(v))
+}
+
+// PANIC linter auto println
+func (l *FactorLog) PANIC(v ...interface{}) {
+	l.output(PanicLevel, textArgs(v))
+}
+
+// FATAL linter auto println
+func (l *FactorLog) FATAL(v ...interface{}) {
+	l.output(FatalLevel, textArgs(v))
+}
+
+// Clock return the clock stamping entries
+func (l *FactorLog) Clock() Clock {
+	return l.clock
+}
+
+// Sync commit the outputs if they are files
+func (l *FactorLog) Sync() error {
+	return l.out.Sync()
+}
+
+// WriteEntry write a prepared entry with its own time
+func (l *FactorLog) WriteEntry(e *Entry) error {
+	return l.write(log.LogContext{
+		Time:     e.Time,
+		Severity: severity(e.Level),
+		Args:     []interface{}{e.Text()},
+	})
+}
+
+// LogEntry write a live entry with its own time
+func (l *FactorLog) LogEntry(e *Entry) {
+	l.WriteEntry(e)
+}
+
+// output format values of a level method, stamped by the clock
+func (l *FactorLog) output(level Level, v []interface{}) error {
+	context := log.LogContext{
+		Time:     l.clock.Now(),
+		Severity: severity(level),
+		Args:     v,
+	}
+	if l.formatter.ShouldRuntimeCaller() {
+		// skip output and the level method
+		pc, file, line, ok := runtime.Caller(2)
+		if !ok {
+			file = "???"
+		} else if fn := runtime.FuncForPC(pc); fn != nil {
+			context.Function = fn.Name()
+		}
+		context.File = file
+		context.Line = line
+	}
+	return l.write(context)
+}
+
+// write format context with the message escaped, see AppendEscaped
+func (l *FactorLog) write(context log.LogContext) error {
+	if l.utc {
+		context.Time = context.Time.UTC()
+	}
+	context.Args = []interface{}{string(AppendEscaped(nil, fmt.Sprint(context.Args...), l.multiline))}
+	l.fmtMutex.Lock()
+	defer l.fmtMutex.Unlock()
+	return l.out.write(levelOf(context.Severity), l.formatter.Format(context))
+}
+
+// textArgs render fields of v as key=value after the message
+func textArgs(v []interface{}) []interface{} {
+	for _, value := range v {
+		if _, ok := value.(Field); ok {
+			return []interface{}{NewEntry(InfoLevel, v...).Text()}
+		}
+	}
+	return v
+}
+
+// severity convert level to factorlog severity
+func severity(level Level) log.Severity {
+	switch level {
+	case DebugLevel:
+		return log.DEBUG
+	case InfoLevel:
+		return log.INFO
+	case WarnLevel:
+		return log.WARN
+	case PanicLevel:
+		return log.PANIC
+	case FatalLevel:
+		return log.FATAL
+	}
+	return log.ERROR
+}
+
+// levelOf convert factorlog severity to level
+func levelOf(s log.Severity) Level {
+	switch s {
+	case log.DEBUG:
+		return DebugLevel
+	case log.INFO:
+		return InfoLevel
+	case log.WARN:
+		return WarnLevel
+	case log.PANIC:
+		return PanicLevel
+	case log.FATAL:
+		return FatalLevel
+	}
+	return ErrorLevel
 }
 
 // STACK linter auto println
@@ -105,11 +234,17 @@ func (l *FactorLog) setStack(id string, count int) {
 
 // serve print stacking
 func (l *FactorLog) serve() {
-	ticker := time.NewTicker(time.Duration(getInterval()) * time.Second)
-	for range ticker.C {
+	ticker := l.clock.NewTicker(time.Duration(getInterval()) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C() {
 		if stacks := l.getStacks(); stacks != nil {
-			// capture current stacks
+			// capture current stacks, an empty dump would use a sequence number
 			tmp := stacks.Capture()
+			if len(tmp) == 0 {
+				continue
+			}
+			tmp[SeqKey] = NextSeq()
+			tmp[SessionKey] = session
 			spew.Dump(tmp)
 		}
 	}
@@ -1,6 +1,14 @@
 package logger
 
-func TestLogger() {
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
 	log := NewFactorLog()
 	log.ERROR("Severity: Error occurred")
 	log.WARN("Severity: Warning!!!")
@@ -8,3 +16,86 @@ func TestLogger() {
 	log.DEBUG("Severity: Debug what?")
 	// log.STACK("Stack from func")
 }
+
+func TestNoColorOnBuffer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := NewFactorLog(WithOutput(buf))
+	log.ERROR("no color")
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Fatalf("unexpected ansi escape in %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "[ERROR] [no color]") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestColorEnv(t *testing.T) {
+	defer os.Unsetenv("FORCE_COLOR")
+	defer os.Unsetenv("NO_COLOR")
+
+	os.Setenv("FORCE_COLOR", "1")
+	buf := &bytes.Buffer{}
+	NewFactorLog(WithOutput(buf)).ERROR("forced")
+	if !strings.Contains(buf.String(), "\x1b[") {
+		t.Fatalf("expected ansi escape in %q", buf.String())
+	}
+
+	os.Setenv("NO_COLOR", "")
+	buf.Reset()
+	NewFactorLog(WithOutput(buf)).ERROR("empty")
+	if !strings.Contains(buf.String(), "\x1b[") {
+		t.Fatalf("empty NO_COLOR must not disable color in %q", buf.String())
+	}
+
+	os.Setenv("NO_COLOR", "1")
+	buf.Reset()
+	NewFactorLog(WithOutput(buf)).ERROR("disabled")
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Fatalf("unexpected ansi escape in %q", buf.String())
+	}
+
+	buf.Reset()
+	NewFactorLog(WithOutput(buf), WithColor(ColorAlways)).ERROR("always")
+	if !strings.Contains(buf.String(), "\x1b[") {
+		t.Fatalf("expected ansi escape in %q", buf.String())
+	}
+}
+
+func TestFormatAndScheme(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := NewFactorLog(
+		WithOutput(buf),
+		WithFormat(`%{SEV} %{Message}`),
+		WithColorScheme(ColorScheme{"WARN": "magenta"}),
+		WithColor(ColorAlways),
+	)
+	log.WARN("custom")
+	log.INFO("plain")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], "\x1b[0;35mWARN custom") {
+		t.Fatalf("unexpected warn line %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "INFO plain") {
+		t.Fatalf("unexpected info line %q", lines[1])
+	}
+}
+
+func TestFactorLogNoEmptyStackDump(t *testing.T) {
+	clock := NewManualClock(time.Unix(0, 0))
+	NewFactorLog(WithOutput(&bytes.Buffer{}), WithClock(clock))
+
+	// intervals without stack entries do not use sequence numbers
+	seq := NextSeq()
+	for i := 0; i < 20; i++ {
+		time.Sleep(time.Millisecond)
+		clock.Add(time.Duration(getInterval()) * time.Second)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := NextSeq(); n != seq+1 {
+		t.Errorf("sequence moved from %d to %d", seq, n)
+	}
+}
@@ -0,0 +1,346 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// minimal msgpack encoding of log values, see https://github.com/msgpack/msgpack/blob/master/spec.md
+
+func appendUint32(buf []byte, u uint32) []byte {
+	return append(buf, byte(u>>24), byte(u>>16), byte(u>>8), byte(u))
+}
+
+func appendUint64(buf []byte, u uint64) []byte {
+	return appendUint32(appendUint32(buf, uint32(u>>32)), uint32(u))
+}
+
+func appendMsgpackNil(buf []byte) []byte {
+	return append(buf, 0xc0)
+}
+
+func appendMsgpackBool(buf []byte, b bool) []byte {
+	if b {
+		return append(buf, 0xc3)
+	}
+	return append(buf, 0xc2)
+}
+
+func appendMsgpackInt(buf []byte, i int64) []byte {
+	switch {
+	case i >= 0:
+		return appendMsgpackUint(buf, uint64(i))
+	case i >= -32:
+		return append(buf, byte(i))
+	case i >= math.MinInt8:
+		return append(buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return append(buf, 0xd1, byte(i>>8), byte(i))
+	case i >= math.MinInt32:
+		return appendUint32(append(buf, 0xd2), uint32(i))
+	}
+	return appendUint64(append(buf, 0xd3), uint64(i))
+}
+
+func appendMsgpackUint(buf []byte, u uint64) []byte {
+	switch {
+	case u < 128:
+		return append(buf, byte(u))
+	case u <= math.MaxUint8:
+		return append(buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return append(buf, 0xcd, byte(u>>8), byte(u))
+	case u <= math.MaxUint32:
+		return appendUint32(append(buf, 0xce), uint32(u))
+	}
+	return appendUint64(append(buf, 0xcf), u)
+}
+
+func appendMsgpackFloat(buf []byte, f float64) []byte {
+	return appendUint64(append(buf, 0xcb), math.Float64bits(f))
+}
+
+// appendMsgpackHeader append a str, bin, array or map header,
+// fix is the fix format of small sizes, 0 if there is none
+func appendMsgpackHeader(buf []byte, n int, fix, fixMax, b8, b16, b32 byte) []byte {
+	switch {
+	case fix != 0 && n <= int(fixMax):
+		return append(buf, fix|byte(n))
+	case b8 != 0 && n <= math.MaxUint8:
+		return append(buf, b8, byte(n))
+	case n <= math.MaxUint16:
+		return append(buf, b16, byte(n>>8), byte(n))
+	}
+	return appendUint32(append(buf, b32), uint32(n))
+}
+
+func appendMsgpackString(buf []byte, s string) []byte {
+	buf = appendMsgpackHeader(buf, len(s), 0xa0, 31, 0xd9, 0xda, 0xdb)
+	return append(buf, s...)
+}
+
+func appendMsgpackBin(buf []byte, b []byte) []byte {
+	buf = appendMsgpackHeader(buf, len(b), 0, 0, 0xc4, 0xc5, 0xc6)
+	return append(buf, b...)
+}
+
+func appendMsgpackArray(buf []byte, n int) []byte {
+	return appendMsgpackHeader(buf, n, 0x90, 15, 0, 0xdc, 0xdd)
+}
+
+func appendMsgpackMap(buf []byte, n int) []byte {
+	return appendMsgpackHeader(buf, n, 0x80, 15, 0, 0xde, 0xdf)
+}
+
+// appendMsgpackEventTime append t as the fluent EventTime extension (type 0)
+func appendMsgpackEventTime(buf []byte, t time.Time) []byte {
+	buf = append(buf, 0xd7, 0x00)
+	buf = appendUint32(buf, uint32(t.Unix()))
+	return appendUint32(buf, uint32(t.Nanosecond()))
+}
+
+// appendMsgpackField append value of f, unknown types are written as text
+func appendMsgpackField(buf []byte, f Field) []byte {
+	switch f.Type {
+	case StringType:
+		return appendMsgpackString(buf, f.Str)
+	case IntType:
+		return appendMsgpackInt(buf, f.Int)
+	case DurationType:
+		return appendMsgpackString(buf, string(appendDuration(nil, time.Duration(f.Int))))
+	}
+
+	switch value := f.Value.(type) {
+	case nil:
+		return appendMsgpackNil(buf)
+	case bool:
+		return appendMsgpackBool(buf, value)
+	case string:
+		return appendMsgpackString(buf, value)
+	case []byte:
+		return appendMsgpackBin(buf, value)
+	case int:
+		return appendMsgpackInt(buf, int64(value))
+	case int8:
+		return appendMsgpackInt(buf, int64(value))
+	case int16:
+		return appendMsgpackInt(buf, int64(value))
+	case int32:
+		return appendMsgpackInt(buf, int64(value))
+	case int64:
+		return appendMsgpackInt(buf, value)
+	case uint:
+		return appendMsgpackUint(buf, uint64(value))
+	case uint8:
+		return appendMsgpackUint(buf, uint64(value))
+	case uint16:
+		return appendMsgpackUint(buf, uint64(value))
+	case uint32:
+		return appendMsgpackUint(buf, uint64(value))
+	case uint64:
+		return appendMsgpackUint(buf, value)
+	case float32:
+		return appendMsgpackFloat(buf, float64(value))
+	case float64:
+		return appendMsgpackFloat(buf, value)
+	}
+	return appendMsgpackString(buf, string(appendTextValue(nil, f)))
+}
+
+// msgpackExt is a decoded extension value
+type msgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+var errMsgpackFormat = errors.New("invalid msgpack")
+
+// readMsgpack decode one value: nil, bool, int64, uint64, float64, string,
+// []byte, []interface{}, map[string]interface{} or msgpackExt
+func readMsgpack(r io.Reader) (interface{}, error) {
+	return readMsgpackValue(r, 0)
+}
+
+// readMsgpackValue decode a value nested in depth arrays and maps
+func readMsgpackValue(r io.Reader, depth int) (interface{}, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return readMsgpackMap(r, int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return readMsgpackArray(r, int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		data, err := readMsgpackBytes(r, int(c&0x1f))
+		return string(data), err
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readMsgpackSize(r, c-0xc4)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackBytes(r, n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readMsgpackSize(r, c-0xc7)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackExt(r, n)
+	case 0xca:
+		data, err := readMsgpackBytes(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 0xcb:
+		data, err := readMsgpackBytes(r, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		data, err := readMsgpackBytes(r, 1<<(c-0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return msgpackUint(data), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		data, err := readMsgpackBytes(r, 1<<(c-0xd0))
+		if err != nil {
+			return nil, err
+		}
+		// sign extend
+		shift := 64 - 8*uint(len(data))
+		return int64(msgpackUint(data)<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return readMsgpackExt(r, 1<<(c-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := readMsgpackSize(r, c-0xd9)
+		if err != nil {
+			return nil, err
+		}
+		data, err := readMsgpackBytes(r, n)
+		return string(data), err
+	case 0xdc, 0xdd:
+		n, err := readMsgpackSize(r, c-0xdc+1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackArray(r, n, depth)
+	case 0xde, 0xdf:
+		n, err := readMsgpackSize(r, c-0xde+1)
+		if err != nil {
+			return nil, err
+		}
+		return readMsgpackMap(r, n, depth)
+	}
+	return nil, fmt.Errorf("%w: byte 0x%x", errMsgpackFormat, c)
+}
+
+func msgpackUint(data []byte) uint64 {
+	var u uint64
+	for _, b := range data {
+		u = u<<8 | uint64(b)
+	}
+	return u
+}
+
+// readMsgpackSize read a size of 1, 2 or 4 bytes for width 0, 1 or 2
+func readMsgpackSize(r io.Reader, width byte) (int, error) {
+	data, err := readMsgpackBytes(r, 1<<width)
+	if err != nil {
+		return 0, err
+	}
+	return int(msgpackUint(data)), nil
+}
+
+// maxMsgpackSize limit size of a decoded str, bin or ext
+const maxMsgpackSize = 64 << 20
+
+func readMsgpackBytes(r io.Reader, n int) ([]byte, error) {
+	if n > maxMsgpackSize {
+		return nil, fmt.Errorf("%w: size %d", errMsgpackFormat, n)
+	}
+	data := make([]byte, n)
+	_, err := io.ReadFull(r, data)
+	return data, err
+}
+
+func readMsgpackExt(r io.Reader, n int) (interface{}, error) {
+	data, err := readMsgpackBytes(r, n+1)
+	if err != nil {
+		return nil, err
+	}
+	return msgpackExt{Type: int8(data[0]), Data: data[1:]}, nil
+}
+
+// maxMsgpackElements limit elements of a decoded array or map,
+// maxMsgpackDepth how deep they are nested
+const (
+	maxMsgpackElements = 1 << 16
+	maxMsgpackDepth    = 32
+)
+
+// checkMsgpackContainer return an error if a container of n elements at depth is over the limits
+func checkMsgpackContainer(n, depth int) error {
+	if n > maxMsgpackElements {
+		return fmt.Errorf("%w: %d elements", errMsgpackFormat, n)
+	}
+	if depth >= maxMsgpackDepth {
+		return fmt.Errorf("%w: nested over %d levels", errMsgpackFormat, maxMsgpackDepth)
+	}
+	return nil
+}
+
+func readMsgpackArray(r io.Reader, n, depth int) (interface{}, error) {
+	if err := checkMsgpackContainer(n, depth); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, n)
+	for i := range values {
+		v, err := readMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func readMsgpackMap(r io.Reader, n, depth int) (interface{}, error) {
+	if err := checkMsgpackContainer(n, depth); err != nil {
+		return nil, err
+	}
+	values := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := readMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		v, err := readMsgpackValue(r, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		values[fmt.Sprint(k)] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"io"
+	"os"
+)
+
+// DefaultFormat is the factorlog format template use by NewFactorLog
+// Colors are added around it depend on the color mode
+const DefaultFormat = `[%{Date}] [%{Time "15:04:05.000000000"}] [%{SEVERITY}] [%{Message}]`
+
+// Option to config FactorLog
+type Option func(*options)
+
+type options struct {
+	out    io.Writer   // destination, default stdout
+	format string      // factorlog format template
+	colors ColorScheme // severity - color name
+	color  ColorMode   // when to write color
+	clock  Clock       // time of entries and tickers
+	utc    bool        // write time in UTC
+	level  Level       // min level written by sinks
+	multi  bool        // indent continuation lines instead of escaping newlines
+
+	highOut   io.Writer   // destination of entries at highLevel and above, nil for out
+	highLevel Level       // min level written to highOut
+	onError   func(error) // called when a stream starts failing
+}
+
+func defaultOptions() *options {
+	return &options{
+		out:    os.Stdout,
+		format: DefaultFormat,
+		colors: DefaultColorScheme(),
+		color:  ColorAuto,
+		clock:  SystemClock,
+	}
+}
+
+// WithOutput set log destination
+func WithOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.out = w
+	}
+}
+
+// WithFormat set factorlog format template
+// Read factorlog.NewStdFormatter for available verbs
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithColorScheme set color of each severity
+func WithColorScheme(scheme ColorScheme) Option {
+	return func(o *options) {
+		o.colors = scheme
+	}
+}
+
+// WithColor set color mode
+func WithColor(mode ColorMode) Option {
+	return func(o *options) {
+		o.color = mode
+	}
+}
+
+// WithClock set the clock stamping entries and driving tickers
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithUTC write time in UTC instead of local time
+func WithUTC() Option {
+	return func(o *options) {
+		o.utc = true
+	}
+}
+
+// WithLevel set the min level written by a WriterSink
+func WithLevel(level Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithMultiline keep newlines of messages and indent the continuation lines,
+// by default they are escaped like other control characters
+func WithMultiline() Option {
+	return func(o *options) {
+		o.multi = true
+	}
+}
+
+// WithLevelOutput write entries of level and above to w instead of the output,
+// with the same format
+func WithLevelOutput(w io.Writer, level Level) Option {
+	return func(o *options) {
+		o.highOut = w
+		o.highLevel = level
+	}
+}
+
+// WithStderr write WARN and above to stderr, the other entries to the output
+func WithStderr() Option {
+	return WithLevelOutput(os.Stderr, WarnLevel)
+}
+
+// WithWriteError set the func called when writing to an output fails, once
+// until a write to it succeeds again. Default print the error on stderr.
+func WithWriteError(fn func(error)) Option {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// build return full factorlog format with colors if enable
+func (o *options) build() string {
+	if !useColor(o.color, o.out) || (o.highOut != nil && !useColor(o.color, o.highOut)) {
+		return o.format
+	}
+	return o.colors.format() + o.format + `%{Color "reset"}`
+}
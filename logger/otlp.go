@@ -0,0 +1,512 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLPEncoding is the body encoding of OTLP/HTTP requests
+type OTLPEncoding int
+
+const (
+	// OTLPProtobuf send application/x-protobuf bodies
+	OTLPProtobuf OTLPEncoding = iota
+	// OTLPJSON send application/json bodies
+	OTLPJSON
+)
+
+// OTLPConfig config an OTLPExporter
+type OTLPConfig struct {
+	URL        string            // logs endpoint, e.g. http://localhost:4318/v1/logs
+	Encoding   OTLPEncoding      // request body encoding
+	Headers    map[string]string // added to every request, e.g. authorization
+	Resource   []Field           // resource attributes, e.g. service.name
+	Scope      string            // instrumentation scope name, default gologs
+	BatchSize  int               // max records of a request, default 512
+	Interval   time.Duration     // send a pending batch every Interval, default 5s
+	Timeout    time.Duration     // timeout of a request, default 10s
+	Backoff    time.Duration     // first retry delay, doubled on each retry, default 1s
+	MaxElapsed time.Duration     // stop retrying a batch after MaxElapsed, default 1m
+	Clock      Clock             // default SystemClock
+}
+
+// OTLPExporter is a Log exporting entries as OTLP ExportLogsServiceRequest over HTTP.
+// Levels are mapped to SeverityNumber, fields to attributes and the trace_id
+// and span_id fields (see WithTrace) to the record trace context.
+// Batches are sent by one goroutine, failed requests are retried as the
+// OTLP spec says: on network errors and status 429, 502, 503 and 504,
+// with exponential backoff or the Retry-After delay.
+type OTLPExporter struct {
+	leveled
+	*stackCounter
+	cfg      OTLPConfig
+	client   *http.Client
+	batch    []*Entry
+	queue    chan []*Entry
+	errors   chan error
+	closed   bool
+	sent     sync.WaitGroup // send goroutine
+	pending  sync.WaitGroup // queued batches
+	done     chan struct{}
+	doneOnce sync.Once
+	mutex    sync.Mutex
+}
+
+// NewOTLPExporter return an exporter posting to cfg.URL
+func NewOTLPExporter(cfg OTLPConfig) *OTLPExporter {
+	if cfg.Scope == "" {
+		cfg.Scope = "gologs"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.MaxElapsed <= 0 {
+		cfg.MaxElapsed = time.Minute
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock
+	}
+	x := &OTLPExporter{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		queue:  make(chan []*Entry, 16),
+		errors: make(chan error, 100),
+		done:   make(chan struct{}),
+	}
+	x.leveled = x.log
+	x.stackCounter = newStackCounter(cfg.Clock, x.WriteEntry)
+	x.sent.Add(1)
+	go x.send()
+	go x.serve()
+	return x
+}
+
+// Errors return export errors, they are dropped when nobody reads
+func (x *OTLPExporter) Errors() <-chan error {
+	return x.errors
+}
+
+func (x *OTLPExporter) report(err error) {
+	select {
+	case x.errors <- err:
+	default:
+	}
+}
+
+func (x *OTLPExporter) log(level Level, v ...interface{}) {
+	e := NewEntry(level, v...)
+	e.Time = x.cfg.Clock.Now()
+	x.WriteEntry(e)
+}
+
+// WriteEntry add e to the batch
+func (x *OTLPExporter) WriteEntry(e *Entry) error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if x.closed {
+		return fmt.Errorf("otlp exporter is closed")
+	}
+	x.batch = append(x.batch, e)
+	if len(x.batch) >= x.cfg.BatchSize {
+		x.flush()
+	}
+	return nil
+}
+
+// Flush queue the pending batch
+func (x *OTLPExporter) Flush() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if !x.closed {
+		x.flush()
+	}
+}
+
+// Sync queue pending stack counters and batch then wait until queued batches are exported
+func (x *OTLPExporter) Sync() error {
+	x.dumpStacks()
+	x.Flush()
+	x.pending.Wait()
+	return nil
+}
+
+func (x *OTLPExporter) flush() {
+	if len(x.batch) == 0 {
+		return
+	}
+	x.pending.Add(1)
+	select {
+	case x.queue <- x.batch:
+	default:
+		x.pending.Done()
+		x.report(fmt.Errorf("otlp queue is full, drop %d records", len(x.batch)))
+	}
+	x.batch = nil
+}
+
+// serve queue pending batch every interval
+func (x *OTLPExporter) serve() {
+	ticker := x.cfg.Clock.NewTicker(x.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			x.Flush()
+		case <-x.done:
+			return
+		}
+	}
+}
+
+// send export queued batches
+func (x *OTLPExporter) send() {
+	defer x.sent.Done()
+	for batch := range x.queue {
+		if err := x.export(batch); err != nil {
+			x.report(err)
+		}
+		x.pending.Done()
+	}
+}
+
+// export post a batch, retrying retryable failures until MaxElapsed
+func (x *OTLPExporter) export(batch []*Entry) error {
+	body, contentType := x.encode(batch)
+	start := time.Now()
+	delay := x.cfg.Backoff
+	for {
+		retry, wait, err := x.post(body, contentType)
+		if err == nil || !retry {
+			return err
+		}
+		if wait <= 0 {
+			// random delay between delay/2 and delay
+			wait = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+			delay *= 2
+		}
+		if time.Since(start)+wait > x.cfg.MaxElapsed {
+			return fmt.Errorf("otlp export of %d records: %w", len(batch), err)
+		}
+		time.Sleep(wait)
+	}
+}
+
+// post send one request, return if it can be retried and the server delay
+func (x *OTLPExporter) post(body []byte, contentType string) (bool, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, x.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range x.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := x.client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return false, 0, x.partialSuccess(data, resp.Header.Get("Content-Type"))
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		wait := time.Duration(0)
+		if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s >= 0 {
+			wait = time.Duration(s) * time.Second
+		}
+		return true, wait, fmt.Errorf("otlp export: %s", resp.Status)
+	}
+	return false, 0, fmt.Errorf("otlp export: %s: %s", resp.Status, bytes.TrimSpace(data))
+}
+
+// partialSuccess return an error if the response rejected records
+func (x *OTLPExporter) partialSuccess(data []byte, contentType string) error {
+	var rejected int64
+	var message string
+	if strings.HasPrefix(contentType, "application/json") {
+		var resp struct {
+			PartialSuccess struct {
+				RejectedLogRecords json.Number `json:"rejectedLogRecords"`
+				ErrorMessage       string      `json:"errorMessage"`
+			} `json:"partialSuccess"`
+		}
+		if json.Unmarshal(data, &resp) == nil {
+			rejected, _ = resp.PartialSuccess.RejectedLogRecords.Int64()
+			message = resp.PartialSuccess.ErrorMessage
+		}
+	} else if fields, err := readProtoFields(data); err == nil {
+		for _, f := range fields {
+			if f.Num != 1 || f.Wire != protoBytes {
+				continue
+			}
+			partial, _ := readProtoFields(f.Bytes)
+			for _, p := range partial {
+				switch p.Num {
+				case 1:
+					rejected = int64(p.Int)
+				case 2:
+					message = string(p.Bytes)
+				}
+			}
+		}
+	}
+	if rejected == 0 && message == "" {
+		return nil
+	}
+	return fmt.Errorf("otlp export: %d records rejected: %s", rejected, message)
+}
+
+// Close send pending stack counters and batches then stop the exporter
+func (x *OTLPExporter) Close() error {
+	x.Stop()
+	x.doneOnce.Do(func() {
+		close(x.done)
+		x.mutex.Lock()
+		if len(x.batch) > 0 {
+			x.pending.Add(1)
+			x.queue <- x.batch
+			x.batch = nil
+		}
+		x.closed = true
+		close(x.queue)
+		x.mutex.Unlock()
+	})
+	x.sent.Wait()
+	return nil
+}
+
+// otlpSeverity return SeverityNumber of level
+func otlpSeverity(level Level) int {
+	switch level {
+	case DebugLevel:
+		return 5
+	case InfoLevel:
+		return 9
+	case WarnLevel:
+		return 13
+	case PanicLevel, FatalLevel:
+		return 21
+	}
+	return 17
+}
+
+// otlpRecord is an entry with its trace context out of the attributes
+type otlpRecord struct {
+	*Entry
+	attributes []Field
+	traceID    []byte
+	spanID     []byte
+}
+
+func newOTLPRecord(e *Entry) otlpRecord {
+	r := otlpRecord{Entry: e}
+	for _, field := range e.Fields {
+		if field.Type == StringType {
+			if field.Key == TraceKey && r.traceID == nil {
+				if id, err := hex.DecodeString(field.Str); err == nil && len(id) == 16 {
+					r.traceID = id
+					continue
+				}
+			}
+			if field.Key == SpanKey && r.spanID == nil {
+				if id, err := hex.DecodeString(field.Str); err == nil && len(id) == 8 {
+					r.spanID = id
+					continue
+				}
+			}
+		}
+		r.attributes = append(r.attributes, field)
+	}
+	return r
+}
+
+// encode return request body and content type of a batch
+func (x *OTLPExporter) encode(batch []*Entry) ([]byte, string) {
+	records := make([]otlpRecord, len(batch))
+	for i, e := range batch {
+		records[i] = newOTLPRecord(e)
+	}
+	observed := x.cfg.Clock.Now()
+	if x.cfg.Encoding == OTLPJSON {
+		return x.encodeJSON(records, observed), "application/json"
+	}
+	return x.encodeProtobuf(records, observed), "application/x-protobuf"
+}
+
+// encodeJSON write the request with the OTLP/JSON mapping:
+// int64 as strings, ids as hex and enums as numbers
+func (x *OTLPExporter) encodeJSON(records []otlpRecord, observed time.Time) []byte {
+	buf := append([]byte(nil), `{"resourceLogs":[{"resource":{"attributes":`...)
+	buf = appendOTLPJSONAttributes(buf, x.cfg.Resource)
+	buf = append(buf, `},"scopeLogs":[{"scope":{"name":`...)
+	buf = appendJSONString(buf, x.cfg.Scope)
+	buf = append(buf, `},"logRecords":[`...)
+	for i, r := range records {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"timeUnixNano":"`...)
+		buf = strconv.AppendInt(buf, r.Time.UnixNano(), 10)
+		buf = append(buf, `","observedTimeUnixNano":"`...)
+		buf = strconv.AppendInt(buf, observed.UnixNano(), 10)
+		buf = append(buf, `","severityNumber":`...)
+		buf = strconv.AppendInt(buf, int64(otlpSeverity(r.Level)), 10)
+		buf = append(buf, `,"severityText":"`...)
+		buf = append(buf, r.Level.String()...)
+		buf = append(buf, `","body":{"stringValue":`...)
+		buf = appendJSONString(buf, r.Message)
+		buf = append(buf, `},"attributes":`...)
+		buf = appendOTLPJSONAttributes(buf, r.attributes)
+		if r.traceID != nil {
+			buf = append(buf, `,"traceId":"`...)
+			buf = append(buf, hex.EncodeToString(r.traceID)...)
+			buf = append(buf, '"')
+		}
+		if r.spanID != nil {
+			buf = append(buf, `,"spanId":"`...)
+			buf = append(buf, hex.EncodeToString(r.spanID)...)
+			buf = append(buf, '"')
+		}
+		buf = append(buf, '}')
+	}
+	return append(buf, "]}]}]}"...)
+}
+
+func appendOTLPJSONAttributes(buf []byte, fields []Field) []byte {
+	buf = append(buf, '[')
+	for i, f := range fields {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"key":`...)
+		buf = appendJSONString(buf, f.Key)
+		buf = append(buf, `,"value":{`...)
+		switch value := otlpValue(f).(type) {
+		case bool:
+			buf = append(buf, `"boolValue":`...)
+			buf = strconv.AppendBool(buf, value)
+		case int64:
+			buf = append(buf, `"intValue":"`...)
+			buf = strconv.AppendInt(buf, value, 10)
+			buf = append(buf, '"')
+		case float64:
+			buf = append(buf, `"doubleValue":`...)
+			buf = strconv.AppendFloat(buf, value, 'g', -1, 64)
+		case string:
+			buf = append(buf, `"stringValue":`...)
+			buf = appendJSONString(buf, value)
+		}
+		buf = append(buf, "}}"...)
+	}
+	return append(buf, ']')
+}
+
+// otlpValue return value of f as bool, int64, float64 or string
+func otlpValue(f Field) interface{} {
+	switch f.Type {
+	case StringType:
+		return f.Str
+	case IntType:
+		return f.Int
+	}
+	switch value := f.Value.(type) {
+	case bool:
+		return value
+	case int:
+		return int64(value)
+	case int32:
+		return int64(value)
+	case int64:
+		return value
+	case uint32:
+		return int64(value)
+	case float32:
+		return float64(value)
+	case float64:
+		return value
+	}
+	return string(appendTextValue(nil, f))
+}
+
+// encodeProtobuf write the request as protobuf, field numbers of
+// opentelemetry/proto/collector/logs/v1 and opentelemetry/proto/logs/v1
+func (x *OTLPExporter) encodeProtobuf(records []otlpRecord, observed time.Time) []byte {
+	return appendProtoMessage(nil, 1, func(buf []byte) []byte { // ResourceLogs
+		buf = appendProtoMessage(buf, 1, func(buf []byte) []byte { // Resource
+			return appendOTLPProtoAttributes(buf, 1, x.cfg.Resource)
+		})
+		return appendProtoMessage(buf, 2, func(buf []byte) []byte { // ScopeLogs
+			buf = appendProtoMessage(buf, 1, func(buf []byte) []byte { // InstrumentationScope
+				return appendProtoString(buf, 1, x.cfg.Scope)
+			})
+			for _, r := range records {
+				r := r
+				buf = appendProtoMessage(buf, 2, func(buf []byte) []byte { // LogRecord
+					buf = appendProtoFixed64(buf, 1, uint64(r.Time.UnixNano()))
+					buf = appendProtoUint(buf, 2, uint64(otlpSeverity(r.Level)))
+					buf = appendProtoString(buf, 3, r.Level.String())
+					buf = appendProtoMessage(buf, 5, func(buf []byte) []byte {
+						return appendProtoBytes(buf, 1, []byte(r.Message))
+					})
+					buf = appendOTLPProtoAttributes(buf, 6, r.attributes)
+					if r.traceID != nil {
+						buf = appendProtoBytes(buf, 9, r.traceID)
+					}
+					if r.spanID != nil {
+						buf = appendProtoBytes(buf, 10, r.spanID)
+					}
+					return appendProtoFixed64(buf, 11, uint64(observed.UnixNano()))
+				})
+			}
+			return buf
+		})
+	})
+}
+
+// appendOTLPProtoAttributes append fields as repeated KeyValue num
+func appendOTLPProtoAttributes(buf []byte, num int, fields []Field) []byte {
+	for _, f := range fields {
+		f := f
+		buf = appendProtoMessage(buf, num, func(buf []byte) []byte {
+			buf = appendProtoString(buf, 1, f.Key)
+			return appendProtoMessage(buf, 2, func(buf []byte) []byte { // AnyValue, always set its oneof
+				switch value := otlpValue(f).(type) {
+				case bool:
+					if value {
+						return appendProtoUint(buf, 2, 1)
+					}
+					return appendProtoUint(buf, 2, 0)
+				case int64:
+					return appendProtoUint(buf, 3, uint64(value))
+				case float64:
+					return appendProtoDouble(buf, 4, value)
+				case string:
+					return appendProtoBytes(buf, 1, []byte(value))
+				}
+				return buf
+			})
+		})
+	}
+	return buf
+}
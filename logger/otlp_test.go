@@ -0,0 +1,241 @@
+package logger
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+// otlpReceiver record request bodies and answer with status in order, then 200
+type otlpReceiver struct {
+	status []int
+	bodies [][]byte
+	types  []string
+	mutex  sync.Mutex
+}
+
+func (o *otlpReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.bodies = append(o.bodies, body)
+	o.types = append(o.types, r.Header.Get("Content-Type"))
+	if len(o.status) > 0 {
+		status := o.status[0]
+		o.status = o.status[1:]
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(status)
+	}
+}
+
+func (o *otlpReceiver) requests() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.bodies)
+}
+
+func newTestExporter(url string, encoding OTLPEncoding) *OTLPExporter {
+	return NewOTLPExporter(OTLPConfig{
+		URL:        url,
+		Encoding:   encoding,
+		Resource:   []Field{String("service.name", "fwd")},
+		Backoff:    time.Millisecond,
+		MaxElapsed: time.Second,
+		Clock:      NewManualClock(time.Unix(100, 0)),
+	})
+}
+
+func traceEntry() *Entry {
+	ctx, _ := WithTraceParent(context.Background(), "00-"+testTraceID+"-"+testSpanID+"-01")
+	e := NewEntry(WarnLevel, ContextArgs(ctx, []interface{}{"stream closed", Int("size", 1200), Any("ok", true)})...)
+	e.Time = time.Unix(90, 5)
+	return e
+}
+
+func TestOTLPJSON(t *testing.T) {
+	recv := &otlpReceiver{}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	x := newTestExporter(server.URL, OTLPJSON)
+	x.WriteEntry(traceEntry())
+	x.Close()
+
+	if recv.requests() != 1 || recv.types[0] != "application/json" {
+		t.Fatalf("requests %d %v", recv.requests(), recv.types)
+	}
+	var req struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []map[string]interface{}
+			}
+			ScopeLogs []struct {
+				Scope      map[string]string
+				LogRecords []map[string]interface{}
+			}
+		}
+	}
+	if err := json.Unmarshal(recv.bodies[0], &req); err != nil {
+		t.Fatalf("%v: %s", err, recv.bodies[0])
+	}
+	rl := req.ResourceLogs[0]
+	if rl.Resource.Attributes[0]["key"] != "service.name" || rl.ScopeLogs[0].Scope["name"] != "gologs" {
+		t.Errorf("resource %s", recv.bodies[0])
+	}
+	r := rl.ScopeLogs[0].LogRecords[0]
+	if r["timeUnixNano"] != "90000000005" || r["severityNumber"] != 13.0 || r["severityText"] != "WARN" ||
+		r["traceId"] != testTraceID || r["spanId"] != testSpanID {
+		t.Errorf("record %v", r)
+	}
+	if body := r["body"].(map[string]interface{}); body["stringValue"] != "stream closed" {
+		t.Errorf("body %v", body)
+	}
+	attrs, _ := json.Marshal(r["attributes"])
+	if want := `[{"key":"size","value":{"intValue":"1200"}},{"key":"ok","value":{"boolValue":true}}]`; string(attrs) != want {
+		t.Errorf("attributes %s, want %s", attrs, want)
+	}
+}
+
+// protoPath return the first field of each num, going down nested messages
+func protoPath(t *testing.T, b []byte, nums ...int) protoField {
+	var field protoField
+	for _, num := range nums {
+		fields, err := readProtoFields(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, f := range fields {
+			if f.Num == num {
+				field, found = f, true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("field %v not found", nums)
+		}
+		b = field.Bytes
+	}
+	return field
+}
+
+func TestOTLPProtobuf(t *testing.T) {
+	recv := &otlpReceiver{}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	x := newTestExporter(server.URL, OTLPProtobuf)
+	x.WriteEntry(traceEntry())
+	x.Close()
+
+	if recv.requests() != 1 || recv.types[0] != "application/x-protobuf" {
+		t.Fatalf("requests %d %v", recv.requests(), recv.types)
+	}
+	body := recv.bodies[0]
+	if got := string(protoPath(t, body, 1, 1, 1, 1).Bytes); got != "service.name" {
+		t.Errorf("resource attribute key %q", got)
+	}
+	if got := string(protoPath(t, body, 1, 2, 1, 1).Bytes); got != "gologs" {
+		t.Errorf("scope %q", got)
+	}
+	record := []int{1, 2, 2}
+	checks := []struct {
+		num  int
+		want uint64
+	}{{1, 90000000005}, {2, 13}, {11, 100000000000}}
+	for _, c := range checks {
+		if got := protoPath(t, body, append(record, c.num)...).Int; got != c.want {
+			t.Errorf("record field %d = %d, want %d", c.num, got, c.want)
+		}
+	}
+	if got := string(protoPath(t, body, append(record, 5, 1)...).Bytes); got != "stream closed" {
+		t.Errorf("body %q", got)
+	}
+	if got := hex.EncodeToString(protoPath(t, body, append(record, 9)...).Bytes); got != testTraceID {
+		t.Errorf("trace id %s", got)
+	}
+	if got := hex.EncodeToString(protoPath(t, body, append(record, 10)...).Bytes); got != testSpanID {
+		t.Errorf("span id %s", got)
+	}
+	if got := protoPath(t, body, append(record, 6, 2, 3)...).Int; got != 1200 {
+		t.Errorf("size attribute %d", got)
+	}
+}
+
+func TestOTLPRetry(t *testing.T) {
+	recv := &otlpReceiver{status: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	x := newTestExporter(server.URL, OTLPProtobuf)
+	x.INFO("retried")
+	x.Close()
+	if n := recv.requests(); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+	select {
+	case err := <-x.Errors():
+		t.Errorf("unexpected error %v", err)
+	default:
+	}
+
+	// not retryable
+	recv = &otlpReceiver{status: []int{http.StatusBadRequest}}
+	server2 := httptest.NewServer(recv)
+	defer server2.Close()
+	x = newTestExporter(server2.URL, OTLPJSON)
+	x.INFO("dropped")
+	x.Close()
+	if n := recv.requests(); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+	select {
+	case <-x.Errors():
+	default:
+		t.Error("expected an export error")
+	}
+}
+
+func TestOTLPSync(t *testing.T) {
+	recv := &otlpReceiver{}
+	server := httptest.NewServer(recv)
+	defer server.Close()
+
+	x := newTestExporter(server.URL, OTLPJSON)
+	defer x.Close()
+	x.FATAL("shutdown")
+	x.Sync()
+	if n := recv.requests(); n != 1 {
+		t.Fatalf("requests = %d, want 1", n)
+	}
+	if body := string(recv.bodies[0]); !strings.Contains(body, `"severityNumber":21,"severityText":"FATAL"`) {
+		t.Errorf("body %s", body)
+	}
+}
+
+func TestTraceParent(t *testing.T) {
+	ctx, err := WithTraceParent(context.Background(), "00-"+testTraceID+"-"+testSpanID+"-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if traceID, spanID := Trace(ctx); traceID != testTraceID || spanID != testSpanID {
+		t.Errorf("trace %s span %s", traceID, spanID)
+	}
+	for _, header := range []string{"", "00-" + testTraceID, "00-00000000000000000000000000000000-" + testSpanID + "-01", "ff-" + testTraceID + "-" + testSpanID + "-01"} {
+		if _, err := WithTraceParent(context.Background(), header); err == nil {
+			t.Errorf("%q: expected an error", header)
+		}
+	}
+}
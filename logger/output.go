@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// levelOutput write entries below level to low and the others to high, the
+// first error of a stream is reported until a write on it succeeds again
+type levelOutput struct {
+	low     io.Writer
+	high    io.Writer // nil if every entry goes to low
+	level   Level
+	onError func(error)
+	failing [2]int32 // atomic, 1 while the low or high stream fails
+}
+
+func newLevelOutput(low io.Writer, o *options) *levelOutput {
+	w := &levelOutput{
+		low:     low,
+		high:    o.highOut,
+		level:   o.highLevel,
+		onError: o.onError,
+	}
+	if w.onError == nil {
+		w.onError = reportWriteError
+	}
+	return w
+}
+
+// reportWriteError is the default error func, errors go to stderr
+func reportWriteError(err error) {
+	fmt.Fprintln(os.Stderr, "log:", err)
+}
+
+// outputName return the file name of w or its type
+func outputName(w io.Writer) string {
+	if f, ok := w.(*os.File); ok {
+		return f.Name()
+	}
+	return fmt.Sprintf("%T", w)
+}
+
+// write p to the stream of level
+func (w *levelOutput) write(level Level, p []byte) error {
+	out, i := w.low, 0
+	if w.high != nil && level >= w.level {
+		out, i = w.high, 1
+	}
+	_, err := out.Write(p)
+	if err != nil {
+		if atomic.CompareAndSwapInt32(&w.failing[i], 0, 1) {
+			w.onError(fmt.Errorf("write %s: %w", outputName(out), err))
+		}
+		return err
+	}
+	if atomic.LoadInt32(&w.failing[i]) == 1 {
+		atomic.StoreInt32(&w.failing[i], 0)
+	}
+	return nil
+}
+
+// Sync commit both streams
+func (w *levelOutput) Sync() error {
+	err := SyncWriter(w.low)
+	if w.high != nil {
+		if herr := SyncWriter(w.high); err == nil {
+			err = herr
+		}
+	}
+	return err
+}
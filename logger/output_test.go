@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLevelOutput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	s := NewWriterSink(&stdout, &TextEncoder{Time: TimeFormat{Layout: TimeUnix}}, WithLevelOutput(&stderr, WarnLevel))
+	s.INFO("info")
+	s.WARN("warn")
+	s.ERROR("error")
+	s.DEBUG("debug")
+	if got := stdout.String(); !strings.Contains(got, "INFO info") || !strings.Contains(got, "DEBUG debug") || strings.Contains(got, "WARN") {
+		t.Errorf("stdout %q", got)
+	}
+	if got := stderr.String(); !strings.Contains(got, "WARN warn") || !strings.Contains(got, "ERROR error") || strings.Contains(got, "INFO") {
+		t.Errorf("stderr %q", got)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	f := NewFactorLog(WithOutput(&stdout), WithLevelOutput(&stderr, ErrorLevel), WithColor(ColorNever))
+	f.WARN("warn")
+	f.ERROR("error")
+	if !strings.Contains(stdout.String(), "[WARN] [warn]") || !strings.Contains(stderr.String(), "[ERROR] [error]") {
+		t.Errorf("stdout %q, stderr %q", stdout.String(), stderr.String())
+	}
+}
+
+// failWriter fail while err is set
+type failWriter struct {
+	err error
+	buf bytes.Buffer
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.buf.Write(p)
+}
+
+func TestLevelOutputError(t *testing.T) {
+	var stdout bytes.Buffer
+	stderr := &failWriter{err: errors.New("broken pipe")}
+	var reported []error
+	s := NewWriterSink(&stdout, &TextEncoder{}, WithLevelOutput(stderr, WarnLevel), WithWriteError(func(err error) {
+		reported = append(reported, err)
+	}))
+
+	if err := s.WriteEntry(&Entry{Level: ErrorLevel, Message: "a"}); err == nil {
+		t.Error("expected the write error")
+	}
+	s.ERROR("b")
+	s.INFO("c")
+	if len(reported) != 1 || !strings.Contains(reported[0].Error(), "broken pipe") || stdout.Len() == 0 {
+		t.Fatalf("reported %v, stdout %q", reported, stdout.String())
+	}
+
+	stderr.err = nil
+	s.ERROR("d")
+	stderr.err = errors.New("closed")
+	s.ERROR("e")
+	if len(reported) != 2 || !strings.Contains(stderr.buf.String(), "d") {
+		t.Errorf("reported %v, stderr %q", reported, stderr.buf.String())
+	}
+}
+
+func TestSyncPipe(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	go ioutil.ReadAll(r)
+
+	// fsync of a pipe fails with EINVAL, there is nothing to commit
+	for _, l := range []Log{
+		NewWriterSink(w, &TextEncoder{}, WithLevelOutput(w, WarnLevel)),
+		NewFactorLog(WithOutput(w), WithColor(ColorNever)),
+	} {
+		l.INFO("piped")
+		if err := Sync(l); err != nil {
+			t.Errorf("%T: %v", l, err)
+		}
+	}
+}
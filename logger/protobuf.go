@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"errors"
+	"math"
+)
+
+// minimal protobuf wire encoding, see https://protobuf.dev/programming-guides/encoding/
+
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+func appendProtoVarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+func appendProtoTag(buf []byte, num int, wire int) []byte {
+	return appendProtoVarint(buf, uint64(num)<<3|uint64(wire))
+}
+
+// appendProtoUint append a varint field, 0 is written too as oneof values need it
+func appendProtoUint(buf []byte, num int, v uint64) []byte {
+	return appendProtoVarint(appendProtoTag(buf, num, protoVarint), v)
+}
+
+func appendProtoFixed64(buf []byte, num int, v uint64) []byte {
+	buf = appendProtoTag(buf, num, protoFixed64)
+	for i := 0; i < 8; i++ {
+		buf = append(buf, byte(v>>(8*i)))
+	}
+	return buf
+}
+
+func appendProtoDouble(buf []byte, num int, v float64) []byte {
+	return appendProtoFixed64(buf, num, math.Float64bits(v))
+}
+
+func appendProtoBytes(buf []byte, num int, b []byte) []byte {
+	buf = appendProtoTag(buf, num, protoBytes)
+	buf = appendProtoVarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+func appendProtoString(buf []byte, num int, s string) []byte {
+	if s == "" {
+		return buf
+	}
+	buf = appendProtoTag(buf, num, protoBytes)
+	buf = appendProtoVarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// appendProtoMessage append the message written by fn as field num
+func appendProtoMessage(buf []byte, num int, fn func(buf []byte) []byte) []byte {
+	return appendProtoBytes(buf, num, fn(nil))
+}
+
+var errProtoFormat = errors.New("invalid protobuf")
+
+// protoField is a decoded field, Bytes is set for length delimited fields
+type protoField struct {
+	Num   int
+	Wire  int
+	Int   uint64
+	Bytes []byte
+}
+
+// readProtoFields decode the fields of a message
+func readProtoFields(b []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(b) > 0 {
+		tag, n := readProtoVarint(b)
+		if n == 0 {
+			return nil, errProtoFormat
+		}
+		b = b[n:]
+		f := protoField{Num: int(tag >> 3), Wire: int(tag & 7)}
+		switch f.Wire {
+		case protoVarint:
+			f.Int, n = readProtoVarint(b)
+			if n == 0 {
+				return nil, errProtoFormat
+			}
+		case protoFixed64, protoFixed32:
+			n = 8
+			if f.Wire == protoFixed32 {
+				n = 4
+			}
+			if len(b) < n {
+				return nil, errProtoFormat
+			}
+			for i := n - 1; i >= 0; i-- {
+				f.Int = f.Int<<8 | uint64(b[i])
+			}
+		case protoBytes:
+			size, m := readProtoVarint(b)
+			if m == 0 || uint64(len(b)-m) < size {
+				return nil, errProtoFormat
+			}
+			f.Bytes = b[m : m+int(size)]
+			n = m + int(size)
+		default:
+			return nil, errProtoFormat
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// readProtoVarint return value and its size, 0 if b is not a varint
+func readProtoVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i] < 0x80 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// Recorder is a flight recorder of a named log.
+// It keeps the last entries of every level in a ring buffer, even the ones
+// below its level, and dumps them to the next log when an ERROR is logged,
+// a panic is recovered or Dump is called.
+type Recorder struct {
+	leveled
+	name    string
+	next    Log
+	level   Level    // min level written to next
+	entries []*Entry // ring buffer
+	pos     int      // next write position
+	count   int      // number of entries in ring
+	mutex   sync.Mutex
+}
+
+// NewRecorder return a recorder keeping size entries of name
+// Entries below level are only recorded, not written to next
+func NewRecorder(name string, next Log, size int, level Level) *Recorder {
+	if size < 0 {
+		size = 0
+	}
+	r := &Recorder{
+		name:    name,
+		next:    next,
+		level:   level,
+		entries: make([]*Entry, size),
+	}
+	r.leveled = r.log
+	return r
+}
+
+// Name linter
+func (r *Recorder) Name() string {
+	return r.name
+}
+
+// Next return the wrapped log
+func (r *Recorder) Next() Log {
+	return r.next
+}
+
+// STACK linter
+func (r *Recorder) STACK(v ...string) {
+	r.next.STACK(v...)
+}
+
+// WriteEntry record and write a prepared entry whatever its level
+func (r *Recorder) WriteEntry(e *Entry) error {
+	r.record(e)
+	return WriteEntry(r.next, e)
+}
+
+// log record the entry, write it if enabled and dump on error.
+// Live entries go through the level methods of next so a sampler behind
+// can drop them, dumped entries are written as is.
+func (r *Recorder) log(level Level, v ...interface{}) {
+	e := NewEntry(level, v...)
+	if level >= r.level {
+		Emit(r.next, level, v...)
+	}
+	if level >= ErrorLevel {
+		// dump the context before the error and start over
+		r.dump("error", true)
+	}
+	r.record(e)
+}
+
+// LogEntry record e, write it if enabled and dump on error, like the level methods
+func (r *Recorder) LogEntry(e *Entry) {
+	if e.Level >= r.level {
+		LogEntry(r.next, e)
+	}
+	if e.Level >= ErrorLevel {
+		r.dump("error", true)
+	}
+	r.record(e)
+}
+
+func (r *Recorder) record(e *Entry) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.entries) == 0 {
+		return
+	}
+	r.entries[r.pos] = e
+	r.pos = (r.pos + 1) % len(r.entries)
+	if r.count < len(r.entries) {
+		r.count++
+	}
+}
+
+// Entries return recorded entries from oldest to newest
+func (r *Recorder) Entries() []*Entry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.snapshot()
+}
+
+func (r *Recorder) snapshot() []*Entry {
+	result := make([]*Entry, 0, r.count)
+	if r.count == 0 {
+		return result
+	}
+	start := (r.pos - r.count + len(r.entries)) % len(r.entries)
+	for i := 0; i < r.count; i++ {
+		result = append(result, r.entries[(start+i)%len(r.entries)])
+	}
+	return result
+}
+
+// Dump write the recorded entries to next, keep them in the ring
+func (r *Recorder) Dump() {
+	r.dump("request", false)
+}
+
+// dump write recorded entries between begin and end markers
+func (r *Recorder) dump(reason string, reset bool) {
+	r.mutex.Lock()
+	entries := r.snapshot()
+	if reset {
+		r.pos = 0
+		r.count = 0
+	}
+	r.mutex.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	r.next.INFO(fmt.Sprintf("[%s] flight recorder dump on %s: %d entries", r.name, reason, len(entries)))
+	for _, e := range entries {
+		WriteEntry(r.next, e)
+	}
+	r.next.INFO(fmt.Sprintf("[%s] flight recorder dump end", r.name))
+}
+
+// LogPanic log a recovered panic value with stack trace and dump the recorder
+func (r *Recorder) LogPanic(value interface{}) {
+	r.log(ErrorLevel, fmt.Sprintf("[%s] panic: %v\n%s", r.name, value, debug.Stack()))
+}
+
+// Recover must be deferred directly, it recovers a panic and log it
+//
+//	defer r.Recover()
+func (r *Recorder) Recover() {
+	if value := recover(); value != nil {
+		r.LogPanic(value)
+	}
+}
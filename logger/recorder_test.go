@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newTestLog(buf *bytes.Buffer) Log {
+	return NewFactorLog(WithOutput(buf), WithFormat(`%{SEVERITY} %{Message}`))
+}
+
+func TestRecorderDumpOnError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewRecorder("fwd", newTestLog(buf), 2, InfoLevel)
+	r.DEBUG("first")
+	r.DEBUG("second")
+	r.INFO("third")
+	if buf.String() != "INFO third\n" {
+		t.Fatalf("debug should not be written, got %q", buf.String())
+	}
+
+	buf.Reset()
+	r.ERROR("boom")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"ERROR boom",
+		"INFO [fwd] flight recorder dump on error: 2 entries",
+		"DEBUG second",
+		"INFO third",
+		"INFO [fwd] flight recorder dump end",
+	}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Fatalf("got %q, want %q", lines, want)
+	}
+
+	// ring was reset after the dump, only the error is kept
+	if entries := r.Entries(); len(entries) != 1 || entries[0].Message != "boom" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+}
+
+func TestRecorderRecover(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewRecorder("worker", newTestLog(buf), 10, InfoLevel)
+	func() {
+		defer r.Recover()
+		r.DEBUG("before panic")
+		panic("oops")
+	}()
+	out := buf.String()
+	if !strings.Contains(out, "[worker] panic: oops") || !strings.Contains(out, "DEBUG before panic") {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestRecorderDump(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewRecorder("x", newTestLog(buf), 10, ErrorLevel)
+	r.WARN("kept")
+	r.Dump()
+	r.Dump()
+	if strings.Count(buf.String(), "WARN kept") != 2 {
+		t.Fatalf("on demand dump should keep entries, got %q", buf.String())
+	}
+}
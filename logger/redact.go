@@ -0,0 +1,232 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RedactMode is how a sensitive value is masked
+type RedactMode int
+
+const (
+	// RedactFull replace the value with [REDACTED]
+	RedactFull RedactMode = iota
+	// RedactPartial keep the first and last 2 characters
+	RedactPartial
+	// RedactHash replace the value with a short sha256, equal values stay comparable
+	RedactHash
+)
+
+// ParseRedactMode return mode of full, partial or hash
+func ParseRedactMode(s string) (RedactMode, error) {
+	switch strings.ToLower(s) {
+	case "", "full":
+		return RedactFull, nil
+	case "partial":
+		return RedactPartial, nil
+	case "hash":
+		return RedactHash, nil
+	}
+	return RedactFull, fmt.Errorf("unknown redact mode %q", s)
+}
+
+// Mask a value with mode
+func (m RedactMode) Mask(value string) string {
+	switch m {
+	case RedactPartial:
+		runes := []rune(value)
+		if len(runes) <= 6 {
+			return strings.Repeat("*", len(runes))
+		}
+		return string(runes[:2]) + strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-2:])
+	case RedactHash:
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return "[REDACTED]"
+}
+
+// RedactPattern mask every match of a regexp in messages and values
+type RedactPattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+	Mode   RedactMode
+}
+
+var (
+	// BearerPattern match bearer tokens in authorization values
+	BearerPattern = RedactPattern{Name: "bearer", Regexp: regexp.MustCompile(`(?i)bearer\s+[a-z0-9\-._~+/]+=*`)}
+	// EmailPattern match email addresses
+	EmailPattern = RedactPattern{Name: "email", Regexp: regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)}
+	// IPv4Pattern match ipv4 addresses
+	IPv4Pattern = RedactPattern{Name: "ipv4", Regexp: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)}
+)
+
+// DefaultPatterns return the built in patterns with mode
+func DefaultPatterns(mode RedactMode) []RedactPattern {
+	patterns := []RedactPattern{BearerPattern, EmailPattern, IPv4Pattern}
+	for i := range patterns {
+		patterns[i].Mode = mode
+	}
+	return patterns
+}
+
+// Redaction is a set of rules to mask sensitive data of entries
+type Redaction struct {
+	fields   map[string]RedactMode // lower case field name - mode
+	patterns []RedactPattern
+}
+
+// NewRedaction return empty rules
+func NewRedaction() *Redaction {
+	return &Redaction{
+		fields: make(map[string]RedactMode),
+	}
+}
+
+// Field mask every value of fields whatever the content, names are case insensitive
+func (r *Redaction) Field(mode RedactMode, names ...string) *Redaction {
+	for _, name := range names {
+		r.fields[strings.ToLower(name)] = mode
+	}
+	return r
+}
+
+// Pattern mask matches of patterns in messages and field values
+func (r *Redaction) Pattern(patterns ...RedactPattern) *Redaction {
+	r.patterns = append(r.patterns, patterns...)
+	return r
+}
+
+// Empty return true if there is no rule
+func (r *Redaction) Empty() bool {
+	return r == nil || (len(r.fields) == 0 && len(r.patterns) == 0)
+}
+
+// Redact return a copy of e with sensitive data masked
+func (r *Redaction) Redact(e *Entry) *Entry {
+	if r.Empty() {
+		return e
+	}
+	redacted := *e
+	redacted.Message = r.redactString(e.Message)
+	if len(e.Fields) > 0 {
+		redacted.Fields = make([]Field, len(e.Fields))
+		for i, field := range e.Fields {
+			redacted.Fields[i] = Field{Key: field.Key, Value: r.redactField(field)}
+		}
+	}
+	return &redacted
+}
+
+// RedactStrings return a copy of v with matches of every pattern masked,
+// for stacks and other raw strings
+func (r *Redaction) RedactStrings(v []string) []string {
+	if r.Empty() {
+		return v
+	}
+	redacted := make([]string, len(v))
+	for i, s := range v {
+		redacted[i] = r.redactString(s)
+	}
+	return redacted
+}
+
+// redactString mask matches of every pattern
+func (r *Redaction) redactString(s string) string {
+	for _, p := range r.patterns {
+		mode := p.Mode
+		s = p.Regexp.ReplaceAllStringFunc(s, func(match string) string {
+			return mode.Mask(match)
+		})
+	}
+	return s
+}
+
+func (r *Redaction) redactField(field Field) interface{} {
+	original := field.Interface()
+	if mode, ok := r.fields[strings.ToLower(field.Key)]; ok {
+		return mode.Mask(fmt.Sprint(original))
+	}
+
+	switch value := original.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return value
+	case string:
+		return r.redactString(value)
+	case error:
+		return r.redactString(value.Error())
+	case fmt.Stringer:
+		return r.redactString(value.String())
+	}
+
+	// composite values are checked on their json form, the masked json
+	// replace the value only if something was found
+	b, err := json.Marshal(original)
+	if err != nil {
+		return r.redactString(fmt.Sprintf("%+v", original))
+	}
+	if redacted := r.redactString(string(b)); redacted != string(b) {
+		return redactedJSON(redacted)
+	}
+	return original
+}
+
+// redactedJSON is a masked json value, written as is by json encoders
+type redactedJSON string
+
+// MarshalJSON linter
+func (r redactedJSON) MarshalJSON() ([]byte, error) {
+	return []byte(r), nil
+}
+
+// String linter
+func (r redactedJSON) String() string {
+	return string(r)
+}
+
+// Redactor mask sensitive data before entries reach next
+type Redactor struct {
+	leveled
+	next  Log
+	rules *Redaction
+}
+
+// NewRedactor return a redactor in front of next
+func NewRedactor(next Log, rules *Redaction) *Redactor {
+	r := &Redactor{
+		next:  next,
+		rules: rules,
+	}
+	r.leveled = r.log
+	return r
+}
+
+// STACK write the stack with matches of patterns masked
+func (r *Redactor) STACK(v ...string) {
+	r.next.STACK(r.rules.RedactStrings(v)...)
+}
+
+// Sync commit next
+func (r *Redactor) Sync() error {
+	return Sync(r.next)
+}
+
+// WriteEntry write a redacted copy of e
+func (r *Redactor) WriteEntry(e *Entry) error {
+	return WriteEntry(r.next, r.rules.Redact(e))
+}
+
+// LogEntry log a redacted copy of e
+func (r *Redactor) LogEntry(e *Entry) {
+	LogEntry(r.next, r.rules.Redact(e))
+}
+
+func (r *Redactor) log(level Level, v ...interface{}) {
+	e := r.rules.Redact(NewEntry(level, v...))
+	Emit(r.next, level, e.Args()...)
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type payload struct {
+	Kind  string `json:"kind"`
+	Owner string `json:"owner"`
+}
+
+var secrets = []string{
+	"abc.def-token",
+	"john@example.com",
+	"10.1.2.3",
+	"client-secret-id",
+}
+
+func redaction(mode RedactMode) *Redaction {
+	return NewRedaction().
+		Field(mode, "client_id", "Password").
+		Pattern(DefaultPatterns(mode)...)
+}
+
+// logSensitive write entries carrying every secret
+func logSensitive(l Log) {
+	l.INFO("auth header Bearer abc.def-token from 10.1.2.3")
+	l.WARN("user", "john@example.com", Any("client_id", "client-secret-id"))
+	l.ERROR("handler err", Any("err", errors.New("send to 10.1.2.3 failed")), Any("password", 1234))
+	l.DEBUG("wrapper", Any("wrapper", payload{Kind: "video", Owner: "john@example.com"}))
+}
+
+func assertNoLeak(t *testing.T, out string) {
+	t.Helper()
+	for _, secret := range secrets {
+		if strings.Contains(out, secret) {
+			t.Fatalf("%q leaked in %q", secret, out)
+		}
+	}
+}
+
+func TestRedactText(t *testing.T) {
+	for _, mode := range []RedactMode{RedactFull, RedactPartial, RedactHash} {
+		buf := &bytes.Buffer{}
+		logSensitive(NewRedactor(NewWriterSink(buf, &TextEncoder{}), redaction(mode)))
+		assertNoLeak(t, buf.String())
+		if !strings.Contains(buf.String(), "wrapper") || !strings.Contains(buf.String(), "video") {
+			t.Fatalf("unexpected output %q", buf.String())
+		}
+	}
+}
+
+func TestRedactFactorLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logSensitive(NewRedactor(newTestLog(buf), redaction(RedactFull)))
+	assertNoLeak(t, buf.String())
+	if !strings.Contains(buf.String(), "client_id=[REDACTED]") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logSensitive(NewRedactor(NewWriterSink(buf, &JSONEncoder{}), redaction(RedactHash)))
+	assertNoLeak(t, buf.String())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	var last map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[3]), &last); err != nil {
+		t.Fatalf("invalid json %q: %v", lines[3], err)
+	}
+	wrapper, ok := last["wrapper"].(map[string]interface{})
+	if !ok || wrapper["kind"] != "video" || !strings.HasPrefix(wrapper["owner"].(string), "sha256:") {
+		t.Fatalf("unexpected wrapper %v", last["wrapper"])
+	}
+}
+
+func TestRedactWriteEntry(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewRedactor(NewWriterSink(buf, &JSONEncoder{}), redaction(RedactFull))
+	r.WriteEntry(NewEntry(DebugLevel, "prepared", Any("client_id", "client-secret-id")))
+	assertNoLeak(t, buf.String())
+}
+
+func TestRedactMask(t *testing.T) {
+	if got := RedactPartial.Mask("0123456789"); got != "01******89" {
+		t.Fatalf("unexpected partial mask %q", got)
+	}
+	if got := RedactPartial.Mask("éèàùçâêî"); got != "éè****êî" {
+		t.Fatalf("partial mask should keep runes, got %q", got)
+	}
+	if RedactHash.Mask("a") != RedactHash.Mask("a") || RedactHash.Mask("a") == RedactHash.Mask("b") {
+		t.Fatal("hash mask should be stable")
+	}
+}
+
+func TestRedactStack(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewRedactor(NewWriterSink(buf, &TextEncoder{}), redaction(RedactFull))
+	r.STACK("goroutine 1 serving john@example.com")
+	r.Sync()
+	if !strings.Contains(buf.String(), "goroutine_1_serving_") {
+		t.Fatalf("stack not written: %q", buf.String())
+	}
+	assertNoLeak(t, buf.String())
+}
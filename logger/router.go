@@ -0,0 +1,316 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RouteOpener open the sink of a route value, closer is called when the route is closed
+type RouteOpener func(value string) (sink Log, closer io.Closer, err error)
+
+// RouterConfig config a Router
+type RouterConfig struct {
+	Key      string        // field key picking the route, e.g. stream_id
+	Open     RouteOpener   // open a sink for a new value
+	Fallback Log           // entries without the key, STACK and open errors, can be nil
+	Idle     time.Duration // close routes unused for Idle, 0 disable
+	MaxOpen  int           // close the least recently used route when full, 0 unlimited
+	Clock    Clock         // default SystemClock
+}
+
+// Router is a Log writing each entry to the sink of its Key field value.
+// Sinks are opened on the first entry of a value.
+type Router struct {
+	leveled
+	cfg      RouterConfig
+	routes   map[string]*route
+	stop     chan struct{}
+	stopOnce sync.Once
+	mutex    sync.Mutex
+}
+
+// route is an open sink, writers hold the read lock so it is not closed while writing
+type route struct {
+	value  string
+	sink   Log
+	closer io.Closer
+	used   time.Time
+	closed bool
+	mutex  sync.RWMutex
+}
+
+// NewRouter return a router, idle routes are checked every cfg.Idle/2
+func NewRouter(cfg RouterConfig) *Router {
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock
+	}
+	r := &Router{
+		cfg:    cfg,
+		routes: make(map[string]*route),
+		stop:   make(chan struct{}),
+	}
+	r.leveled = r.log
+	go r.serve()
+	return r
+}
+
+func (r *Router) log(level Level, v ...interface{}) {
+	e := NewEntry(level, v...)
+	e.Time = r.cfg.Clock.Now()
+	r.WriteEntry(e)
+}
+
+// WriteEntry write e into the sink of its route
+func (r *Router) WriteEntry(e *Entry) error {
+	value, ok := e.Field(r.cfg.Key)
+	if !ok {
+		return r.fallback(e)
+	}
+	name := fmt.Sprint(value)
+
+	// a route closed between get and lock is opened again
+	for {
+		rt, err := r.get(name)
+		if err != nil {
+			if r.cfg.Fallback != nil {
+				Emit(r.cfg.Fallback, WarnLevel, "open log route error:", err, Any(r.cfg.Key, name))
+			}
+			r.fallback(e)
+			return err
+		}
+		rt.mutex.RLock()
+		if !rt.closed {
+			err = WriteEntry(rt.sink, e)
+			rt.mutex.RUnlock()
+			return err
+		}
+		rt.mutex.RUnlock()
+	}
+}
+
+func (r *Router) fallback(e *Entry) error {
+	if r.cfg.Fallback == nil {
+		return nil
+	}
+	return WriteEntry(r.cfg.Fallback, e)
+}
+
+// get return the route of value, open it if needed.
+// The sink is opened without holding the lock, if another writer added the
+// route meanwhile it is used and the new sink closed.
+func (r *Router) get(value string) (*route, error) {
+	now := r.cfg.Clock.Now()
+
+	r.mutex.Lock()
+	rt, ok := r.routes[value]
+	if ok {
+		rt.used = now
+	}
+	r.mutex.Unlock()
+	if ok {
+		return rt, nil
+	}
+
+	sink, closer, err := r.cfg.Open(value)
+	if err != nil {
+		return nil, err
+	}
+	opened := &route{value: value, sink: sink, closer: closer, used: now}
+
+	var evicted *route
+	r.mutex.Lock()
+	select {
+	case <-r.stop:
+		// stopped while opening
+		r.mutex.Unlock()
+		opened.close()
+		return nil, fmt.Errorf("log router is stopped")
+	default:
+	}
+	rt, ok = r.routes[value]
+	if ok {
+		rt.used = now
+	} else {
+		if r.cfg.MaxOpen > 0 && len(r.routes) >= r.cfg.MaxOpen {
+			evicted = r.oldest()
+			delete(r.routes, evicted.value)
+		}
+		rt = opened
+		r.routes[value] = rt
+	}
+	r.mutex.Unlock()
+
+	if ok {
+		opened.close()
+	}
+	if evicted != nil {
+		evicted.close()
+	}
+	return rt, nil
+}
+
+// oldest return the least recently used route
+func (r *Router) oldest() *route {
+	var oldest *route
+	for _, rt := range r.routes {
+		if oldest == nil || rt.used.Before(oldest.used) {
+			oldest = rt
+		}
+	}
+	return oldest
+}
+
+func (rt *route) close() {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	if rt.closed {
+		return
+	}
+	rt.closed = true
+	if rt.closer != nil {
+		rt.closer.Close()
+	}
+}
+
+// Len return number of open routes
+func (r *Router) Len() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.routes)
+}
+
+// STACK linter
+func (r *Router) STACK(v ...string) {
+	if r.cfg.Fallback != nil {
+		r.cfg.Fallback.STACK(v...)
+	}
+}
+
+// Sync sync the sink of every open route and the fallback
+func (r *Router) Sync() error {
+	r.mutex.Lock()
+	routes := make([]*route, 0, len(r.routes))
+	for _, rt := range r.routes {
+		routes = append(routes, rt)
+	}
+	r.mutex.Unlock()
+
+	var err error
+	for _, rt := range routes {
+		rt.mutex.RLock()
+		if !rt.closed {
+			if e := Sync(rt.sink); e != nil && err == nil {
+				err = e
+			}
+		}
+		rt.mutex.RUnlock()
+	}
+	if r.cfg.Fallback != nil {
+		if e := Sync(r.cfg.Fallback); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// closeIdle close routes unused for cfg.Idle at now
+func (r *Router) closeIdle(now time.Time) {
+	var idle []*route
+	r.mutex.Lock()
+	for value, rt := range r.routes {
+		if now.Sub(rt.used) >= r.cfg.Idle {
+			idle = append(idle, rt)
+			delete(r.routes, value)
+		}
+	}
+	r.mutex.Unlock()
+
+	for _, rt := range idle {
+		rt.close()
+	}
+}
+
+func (r *Router) serve() {
+	if r.cfg.Idle <= 0 {
+		return
+	}
+	ticker := r.cfg.Clock.NewTicker(r.cfg.Idle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C():
+			r.closeIdle(now)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Stop close every route
+func (r *Router) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+		r.mutex.Lock()
+		routes := r.routes
+		r.routes = make(map[string]*route)
+		r.mutex.Unlock()
+		for _, rt := range routes {
+			rt.close()
+		}
+	})
+}
+
+// closerFunc is an io.Closer of a func
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// FileRoutes return a RouteOpener writing entries of each value encoded by enc
+// into dir/<value>.log rotated by cfg, cfg.Path is ignored
+func FileRoutes(dir string, enc Encoder, cfg FileConfig) RouteOpener {
+	return func(value string) (Log, io.Closer, error) {
+		cfg := cfg
+		cfg.Path = filepath.Join(dir, RouteFileName(value)+".log")
+		file, err := NewRotatingFile(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		var opts []Option
+		if cfg.Clock != nil {
+			opts = append(opts, WithClock(cfg.Clock))
+		}
+		sink := NewWriterSink(file, enc, opts...)
+		return sink, closerFunc(func() error {
+			sink.Stop()
+			return file.Close()
+		}), nil
+	}
+}
+
+// RouteFileName return value usable as a file name, distinct values have
+// distinct names: letters, digits, '-' and '.' are kept except a leading '.',
+// '_' is written "__" and other bytes "_" and their hex value (e.g. "a/b" is "a_2fb")
+func RouteFileName(value string) string {
+	if value == "" {
+		return "_"
+	}
+	const hex = "0123456789abcdef"
+	name := make([]byte, 0, len(value))
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.' && i > 0:
+			name = append(name, c)
+		case c == '_':
+			name = append(name, '_', '_')
+		default:
+			name = append(name, '_', hex[c>>4], hex[c&0xf])
+		}
+	}
+	return string(name)
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func readRoute(t *testing.T, dir, name string) string {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRouter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "router")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	clock := NewManualClock(time.Unix(100, 0))
+	fallback := &bytes.Buffer{}
+	r := NewRouter(RouterConfig{
+		Key:      "stream_id",
+		Open:     FileRoutes(dir, &TextEncoder{Time: TimeFormat{Layout: TimeUnix}}, FileConfig{Clock: clock}),
+		Fallback: newTestLog(fallback),
+		Idle:     time.Minute,
+		MaxOpen:  2,
+		Clock:    clock,
+	})
+	defer r.Stop()
+
+	r.INFO("start", String("stream_id", "a"))
+	r.INFO("start", String("stream_id", "../b"))
+	r.WARN("no stream")
+	if n := r.Len(); n != 2 {
+		t.Fatalf("open routes = %d, want 2", n)
+	}
+
+	// a is the least recently used
+	clock.Add(time.Second)
+	r.INFO("more", String("stream_id", "../b"))
+	r.INFO("start", String("stream_id", "c"))
+	if n := r.Len(); n != 2 {
+		t.Fatalf("open routes = %d, want 2", n)
+	}
+	if got, want := readRoute(t, dir, "a.log"), "100 INFO start stream_id=a\n"; got != want {
+		t.Errorf("a.log = %q, want %q", got, want)
+	}
+
+	// a is opened again in append mode
+	r.ERROR("again", String("stream_id", "a"))
+	if got, want := readRoute(t, dir, "a.log"), "100 INFO start stream_id=a\n101 ERROR again stream_id=a\n"; got != want {
+		t.Errorf("a.log = %q, want %q", got, want)
+	}
+
+	r.closeIdle(clock.Now().Add(time.Minute))
+	if n := r.Len(); n != 0 {
+		t.Errorf("open routes after idle = %d, want 0", n)
+	}
+	if got, want := readRoute(t, dir, "_2e._2fb.log"), "100 INFO start stream_id=../b\n101 INFO more stream_id=../b\n"; got != want {
+		t.Errorf("_2e._2fb.log = %q, want %q", got, want)
+	}
+	if got, want := fallback.String(), "WARN no stream\n"; got != want {
+		t.Errorf("fallback = %q, want %q", got, want)
+	}
+}
+
+func TestRouteFileName(t *testing.T) {
+	cases := map[string]string{
+		"abc-1_2.x": "abc-1__2.x",
+		"a/b c":     "a_2fb_20c",
+		"a_b":       "a__b",
+		"a_2fb":     "a__2fb",
+		"..":        "_2e.",
+		"é":         "_c3_a9",
+		"":          "_",
+	}
+	for value, want := range cases {
+		if got := RouteFileName(value); got != want {
+			t.Errorf("RouteFileName(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestRouterOpenOutsideLock(t *testing.T) {
+	opening := make(chan struct{})
+	release := make(chan struct{})
+	var opened int32
+	r := NewRouter(RouterConfig{
+		Key: "stream_id",
+		Open: func(value string) (Log, io.Closer, error) {
+			if value == "slow" {
+				close(opening)
+				<-release
+			}
+			atomic.AddInt32(&opened, 1)
+			return NewWriterSink(ioutil.Discard, &TextEncoder{}), nil, nil
+		},
+	})
+	defer r.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		r.INFO("slow open", String("stream_id", "slow"))
+		close(done)
+	}()
+	<-opening
+	r.INFO("fast open", String("stream_id", "fast"))
+	if n := r.Len(); n != 1 {
+		t.Fatalf("a slow open should not block other routes, open routes = %d", n)
+	}
+	close(release)
+	<-done
+	if n := r.Len(); n != 2 || atomic.LoadInt32(&opened) != 2 {
+		t.Fatalf("open routes = %d, opened %d", n, opened)
+	}
+}
@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Sampler throttle repeated messages in front of a log.
+// In each interval the first entries of a key (level and message) are
+// written, then every thereafter-th one. The number of dropped entries
+// is written as a summary at the end of the interval.
+type Sampler struct {
+	leveled
+	next       Log
+	first      int
+	thereafter int
+	interval   time.Duration
+	counters   map[string]*sampleCounter // level + message - counter
+	stop       chan struct{}
+	stopOnce   sync.Once
+	mutex      sync.Mutex
+}
+
+type sampleCounter struct {
+	level      Level
+	message    string
+	count      int // entries in current interval
+	suppressed int // dropped entries in current interval
+}
+
+// NewSampler return a sampler writing to next
+// thereafter <= 0 drop every entry after the first ones, interval <= 0 is a second
+func NewSampler(next Log, interval time.Duration, first, thereafter int) *Sampler {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	s := &Sampler{
+		next:       next,
+		first:      first,
+		thereafter: thereafter,
+		interval:   interval,
+		counters:   make(map[string]*sampleCounter),
+		stop:       make(chan struct{}),
+	}
+	s.leveled = s.log
+	go s.serve()
+	return s
+}
+
+// STACK linter
+func (s *Sampler) STACK(v ...string) {
+	s.next.STACK(v...)
+}
+
+// Stop flush the summary and stop the interval ticker
+func (s *Sampler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		s.Flush()
+	})
+}
+
+// WriteEntry write a prepared entry without sampling
+func (s *Sampler) WriteEntry(e *Entry) error {
+	return WriteEntry(s.next, e)
+}
+
+// LogEntry log e if allowed, entries of the same message are counted together
+func (s *Sampler) LogEntry(e *Entry) {
+	if e.Level >= PanicLevel || s.allow(e.Level, e.Message) {
+		LogEntry(s.next, e)
+	}
+}
+
+// log write the entry if allowed, PANIC and FATAL entries are never dropped
+func (s *Sampler) log(level Level, v ...interface{}) {
+	if level >= PanicLevel || s.allow(level, fmt.Sprint(unstamped(v)...)) {
+		Emit(s.next, level, v...)
+	}
+}
+
+// allow count the entry and check if it should be written
+func (s *Sampler) allow(level Level, message string) bool {
+	key := level.String() + message
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok {
+		c = &sampleCounter{level: level, message: message}
+		s.counters[key] = c
+	}
+	c.count++
+
+	if c.count <= s.first {
+		return true
+	}
+	if s.thereafter > 0 && (c.count-s.first)%s.thereafter == 0 {
+		return true
+	}
+	c.suppressed++
+	return false
+}
+
+// Flush write summaries and start a new interval
+func (s *Sampler) Flush() {
+	s.mutex.Lock()
+	counters := s.counters
+	s.counters = make(map[string]*sampleCounter)
+	s.mutex.Unlock()
+
+	keys := make([]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if c := counters[key]; c.suppressed > 0 {
+			Emit(s.next, c.level, fmt.Sprintf("suppressed %d similar messages: %s", c.suppressed, c.message))
+		}
+	}
+}
+
+// serve flush every interval
+func (s *Sampler) serve() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Flush()
+		case <-s.stop:
+			return
+		}
+	}
+}
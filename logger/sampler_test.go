@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewSampler(newTestLog(buf), time.Hour, 2, 3)
+	defer s.Stop()
+
+	for i := 0; i < 10; i++ {
+		s.INFO("fwd was closed")
+	}
+	s.WARN("other")
+
+	// 1, 2 then 5 and 8
+	if n := strings.Count(buf.String(), "INFO fwd was closed\n"); n != 4 {
+		t.Fatalf("expect 4 entries written, got %d in %q", n, buf.String())
+	}
+	if !strings.Contains(buf.String(), "WARN other\n") {
+		t.Fatalf("other key should not be sampled, got %q", buf.String())
+	}
+
+	buf.Reset()
+	s.Flush()
+	if buf.String() != "INFO suppressed 6 similar messages: fwd was closed\n" {
+		t.Fatalf("unexpected summary %q", buf.String())
+	}
+
+	// a new interval start over
+	buf.Reset()
+	s.INFO("fwd was closed")
+	s.Flush()
+	if buf.String() != "INFO fwd was closed\n" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
+
+func TestSamplerDropAll(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewSampler(newTestLog(buf), time.Hour, 1, 0)
+	for i := 0; i < 5; i++ {
+		s.ERROR("flood")
+	}
+	s.Stop()
+	want := "ERROR flood\nERROR suppressed 4 similar messages: flood\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSamplerKeepFatal(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewSampler(newTestLog(buf), time.Hour, 1, 0)
+	defer s.Stop()
+
+	for i := 0; i < 3; i++ {
+		s.FATAL("shutdown")
+		s.PANIC("bad state")
+	}
+	if n := strings.Count(buf.String(), "FATAL shutdown\n"); n != 3 {
+		t.Errorf("expect 3 fatal entries, got %q", buf.String())
+	}
+	if n := strings.Count(buf.String(), "PANIC bad state\n"); n != 3 {
+		t.Errorf("expect 3 panic entries, got %q", buf.String())
+	}
+}
+
+func TestSamplerBehindRecorder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewSampler(newTestLog(buf), 0, 1, 0)
+	r := NewRecorder("test", s, 10, DebugLevel)
+	r.INFO("b flood")
+	r.INFO("b flood")
+	r.INFO("a flood")
+	r.INFO("a flood")
+	if n := len(r.Entries()); n != 4 {
+		t.Errorf("recorder should keep the sampled entries, got %d", n)
+	}
+	s.Stop()
+	want := "INFO b flood\nINFO a flood\nINFO suppressed 1 similar messages: a flood\nINFO suppressed 1 similar messages: b flood\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	"github.com/segmentio/ksuid"
+)
+
+const (
+	// SeqKey is the field key of the process wide sequence number of an entry
+	SeqKey = "seq"
+	// SessionKey is the field key of the process session id
+	SessionKey = "session_id"
+)
+
+var (
+	sequence uint64
+	session  = GenerateID()
+)
+
+// GenerateID return a new ksuid
+func GenerateID() string {
+	return ksuid.New().String()
+}
+
+// NextSeq return the next sequence number, starting at 1
+func NextSeq() uint64 {
+	return atomic.AddUint64(&sequence, 1)
+}
+
+// Session return the id of this process, it changes at each start
+func Session() string {
+	return session
+}
+
+// StampFields return fields followed by a new sequence number and the session id,
+// the typed counterpart of Stamp
+func StampFields(fields []Field) []Field {
+	stamped := make([]Field, 0, len(fields)+2)
+	stamped = append(stamped, fields...)
+	return append(stamped, Int64(SeqKey, int64(NextSeq())), String(SessionKey, session))
+}
+
+// Stamp return a copy of v followed by fields, a new sequence number and the session id
+func Stamp(v []interface{}, fields ...Field) []interface{} {
+	args := make([]interface{}, 0, len(v)+len(fields)+2)
+	args = append(args, v...)
+	for _, field := range fields {
+		args = append(args, field)
+	}
+	return append(args, Int64(SeqKey, int64(NextSeq())), String(SessionKey, session))
+}
+
+// Sequencer stamp entries with a sequence number and the session id when they
+// reach next, in front of the sinks. Entries filtered or sampled before do not
+// take a number, so a gap in the sequence is an entry lost after this point.
+// Entries already stamped, e.g. shipped from another process, are kept as is.
+type Sequencer struct {
+	leveled
+	next Log
+}
+
+// NewSequencer return a sequencer in front of next, next itself if it is one
+func NewSequencer(next Log) *Sequencer {
+	if s, ok := next.(*Sequencer); ok {
+		return s
+	}
+	s := &Sequencer{next: next}
+	s.leveled = s.log
+	return s
+}
+
+// Next return the log behind the sequencer
+func (s *Sequencer) Next() Log {
+	return s.next
+}
+
+func (s *Sequencer) log(level Level, v ...interface{}) {
+	for _, arg := range v {
+		if field, ok := arg.(Field); ok && field.Key == SeqKey {
+			Emit(s.next, level, v...)
+			return
+		}
+	}
+	Emit(s.next, level, Stamp(v)...)
+}
+
+// STACK linter, stack counters are stamped when the sink writes them
+func (s *Sequencer) STACK(v ...string) {
+	s.next.STACK(v...)
+}
+
+// WriteEntry write a stamped copy of e
+func (s *Sequencer) WriteEntry(e *Entry) error {
+	return WriteEntry(s.next, s.stamp(e))
+}
+
+// LogEntry log a stamped copy of e
+func (s *Sequencer) LogEntry(e *Entry) {
+	LogEntry(s.next, s.stamp(e))
+}
+
+// Clock return the clock of next
+func (s *Sequencer) Clock() Clock {
+	return ClockOf(s.next)
+}
+
+// Sync linter
+func (s *Sequencer) Sync() error {
+	return Sync(s.next)
+}
+
+func (s *Sequencer) stamp(e *Entry) *Entry {
+	if _, ok := e.Field(SeqKey); ok {
+		return e
+	}
+	clone := *e
+	clone.Fields = StampFields(e.Fields)
+	return &clone
+}
+
+// unstamped return v without the fields added by Stamp, v is copied only if it has them
+func unstamped(v []interface{}) []interface{} {
+	for i, value := range v {
+		if field, ok := value.(Field); ok && (field.Key == SeqKey || field.Key == SessionKey) {
+			args := append([]interface{}(nil), v[:i]...)
+			for _, value := range v[i+1:] {
+				if field, ok := value.(Field); !ok || (field.Key != SeqKey && field.Key != SessionKey) {
+					args = append(args, value)
+				}
+			}
+			return args
+		}
+	}
+	return v
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStamp(t *testing.T) {
+	v := []interface{}{"hello", 1}
+	first := Stamp(v, String(CallerKey, "logs/init.go:1"))
+	second := Stamp(v)
+	if len(v) != 2 {
+		t.Fatalf("v modified: %v", v)
+	}
+
+	e1 := NewEntry(InfoLevel, first...)
+	e2 := NewEntry(InfoLevel, second...)
+	seq1, _ := e1.Field(SeqKey)
+	seq2, _ := e2.Field(SeqKey)
+	if seq2.(int64) != seq1.(int64)+1 {
+		t.Errorf("seq %v then %v", seq1, seq2)
+	}
+	if s, _ := e1.Field(SessionKey); s != Session() || len(Session()) != 27 {
+		t.Errorf("session %v, want ksuid %s", s, Session())
+	}
+	if e1.Message != "hello 1" || e1.Fields[0].Key != CallerKey {
+		t.Errorf("entry %s", e1.Text())
+	}
+}
+
+func TestStampConcurrent(t *testing.T) {
+	const n = 100
+	seen := make([]bool, n)
+	start := NextSeq()
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seq, _ := NewEntry(InfoLevel, Stamp(nil)...).Field(SeqKey)
+			mutex.Lock()
+			seen[seq.(int64)-int64(start)-1] = true
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("missing seq %d", int64(start)+int64(i)+1)
+		}
+	}
+}
+
+func TestSamplerIgnoreStamp(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewSampler(newTestLog(buf), time.Hour, 1, 0)
+	defer s.Stop()
+	s.INFO(Stamp([]interface{}{"same"})...)
+	s.INFO(Stamp([]interface{}{"same"})...)
+	if n := strings.Count(buf.String(), "same"); n != 1 {
+		t.Errorf("written %d times, want 1:\n%s", n, buf.String())
+	}
+}
+
+func TestSequencerNoGapOnFilter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	r := NewRecorder("fwd", NewSequencer(NewWriterSink(buf, &JSONEncoder{})), 10, InfoLevel)
+	r.DEBUG("filtered")
+	r.INFO("a")
+	r.DEBUG("filtered")
+	LogEntry(r, &Entry{Level: InfoLevel, Message: "b"})
+	WriteEntry(r.next, &Entry{Level: InfoLevel, Message: "shipped", Fields: []Field{Int64(SeqKey, 7)}})
+
+	var seqs []int64
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e struct{ Seq int64 }
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, e.Seq)
+	}
+	if len(seqs) != 3 || seqs[1] != seqs[0]+1 || seqs[2] != 7 {
+		t.Errorf("seq %v, want 2 consecutive numbers then 7:\n%s", seqs, buf.String())
+	}
+}
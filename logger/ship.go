@@ -0,0 +1,434 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fingerprintSize is the number of first bytes identifying a file in a checkpoint
+const fingerprintSize = 256
+
+// ShipConfig configure a Shipper
+type ShipConfig struct {
+	Paths      []string      // files or glob patterns
+	Decoder    Decoder       // default AutoDecoder
+	Out        Log           // entries are written with WriteEntry
+	Offsets    string        // file keeping the offsets, none if empty
+	PathKey    string        // field with the path of the file, none if empty
+	FromEnd    bool          // files without an offset found at start are read from their end
+	Poll       time.Duration // check files for new lines, default 250ms
+	Rescan     time.Duration // match the glob patterns again, default 10s
+	Checkpoint time.Duration // sync Out and save the offsets, default 1s
+	Backoff    time.Duration // retry a failed write, default 1s
+	Clock      Clock         // time of lines without one, default SystemClock
+}
+
+// shipOffset is the checkpoint of a file: the offset after the last written
+// line, the checksum of the first bytes, up to offset, and the device and
+// inode of the file where the platform has them
+type shipOffset struct {
+	Offset int64  `json:"offset"`
+	Sum    uint32 `json:"crc"`
+	Dev    uint64 `json:"dev,omitempty"`
+	Ino    uint64 `json:"ino,omitempty"`
+}
+
+// setID set the device and inode of info
+func (o *shipOffset) setID(info os.FileInfo) {
+	o.Dev, o.Ino = 0, 0
+	if info != nil {
+		o.Dev, o.Ino, _ = fileID(info)
+	}
+}
+
+// shipFile is a followed file
+type shipFile struct {
+	follower *Follower
+	info     os.FileInfo // file of the checkpoint
+	offset   shipOffset
+}
+
+// Shipper tail files like tail -F and write their lines decoded into a Log.
+// Offsets are saved after Out is synced, so a restarted shipper continue
+// where it stopped. If a file was rotated in between, the rest of the rotated
+// file (found by RotatedFiles, gzip or not) and the files rotated after it
+// are shipped first, oldest first.
+// A failed write is retried until it succeeds or the shipper is stopped.
+type Shipper struct {
+	cfg     ShipConfig
+	files   map[string]*shipFile
+	saved   map[string]shipOffset // offsets read at start
+	errors  chan error
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+	started bool
+}
+
+// NewShipper return a shipper of cfg, reading its offsets file
+func NewShipper(cfg ShipConfig) (*Shipper, error) {
+	if cfg.Decoder == nil {
+		cfg.Decoder = AutoDecoder{}
+	}
+	if cfg.Poll <= 0 {
+		cfg.Poll = 250 * time.Millisecond
+	}
+	if cfg.Rescan <= 0 {
+		cfg.Rescan = 10 * time.Second
+	}
+	if cfg.Checkpoint <= 0 {
+		cfg.Checkpoint = time.Second
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = SystemClock
+	}
+	s := &Shipper{
+		cfg:    cfg,
+		files:  make(map[string]*shipFile),
+		saved:  make(map[string]shipOffset),
+		errors: make(chan error, 100),
+	}
+	if cfg.Offsets != "" {
+		data, err := ioutil.ReadFile(cfg.Offsets)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &s.saved); err != nil {
+				return nil, fmt.Errorf("%s: %w", cfg.Offsets, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Errors return read and write errors, they are dropped when nobody reads
+func (s *Shipper) Errors() <-chan error {
+	return s.errors
+}
+
+func (s *Shipper) report(err error) {
+	select {
+	case s.errors <- err:
+	default:
+	}
+}
+
+// Offsets return the checkpoint offset of each followed file
+func (s *Shipper) Offsets() map[string]int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	offsets := make(map[string]int64, len(s.files))
+	for path, f := range s.files {
+		offsets[path] = f.offset.Offset
+	}
+	return offsets
+}
+
+// Run ship files until stop is closed, then save the offsets a last time
+func (s *Shipper) Run(stop <-chan struct{}) error {
+	s.scan(stop)
+	s.mutex.Lock()
+	s.started = true
+	s.mutex.Unlock()
+
+	rescan := time.NewTicker(s.cfg.Rescan)
+	defer rescan.Stop()
+	checkpoint := time.NewTicker(s.cfg.Checkpoint)
+	defer checkpoint.Stop()
+	for {
+		select {
+		case <-rescan.C:
+			s.scan(stop)
+		case <-checkpoint.C:
+			if err := s.checkpoint(); err != nil {
+				s.report(err)
+			}
+		case <-stop:
+			s.wg.Wait()
+			return s.checkpoint()
+		}
+	}
+}
+
+// scan start a follower of each new path matching the patterns
+func (s *Shipper) scan(stop <-chan struct{}) {
+	for _, pattern := range s.cfg.Paths {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			s.report(err)
+			continue
+		}
+		if len(paths) == 0 && !hasMeta(pattern) {
+			// a file which does not exist yet
+			paths = []string{pattern}
+		}
+		for _, path := range paths {
+			s.mutex.Lock()
+			_, ok := s.files[path]
+			s.mutex.Unlock()
+			if !ok {
+				s.start(path, stop)
+			}
+		}
+	}
+}
+
+// hasMeta check a pattern has glob characters
+func hasMeta(pattern string) bool {
+	for _, c := range pattern {
+		switch c {
+		case '*', '?', '[', '\\':
+			return true
+		}
+	}
+	return false
+}
+
+// start follow path from its saved offset
+func (s *Shipper) start(path string, stop <-chan struct{}) {
+	offset, ok := s.saved[path]
+	if ok {
+		offset = s.resume(path, offset, stop)
+	} else if s.cfg.FromEnd && !s.started {
+		if info, err := os.Stat(path); err == nil {
+			if sum, err := fileSum(path, info.Size()); err == nil {
+				offset = shipOffset{Offset: info.Size(), Sum: sum}
+				offset.setID(info)
+			}
+		}
+	}
+
+	f := &shipFile{
+		follower: NewFollower(path, offset.Offset, s.cfg.Poll),
+		offset:   offset,
+	}
+	s.mutex.Lock()
+	s.files[path] = f
+	s.mutex.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer f.follower.Close()
+		s.follow(f, stop)
+	}()
+}
+
+// resume return the offset to follow path from. If path is not the file of
+// the checkpoint, the rest of the rotated file matching it and the files
+// rotated after it are shipped, then path is followed from its start.
+// The checkpoint is kept if stop is closed meanwhile.
+func (s *Shipper) resume(path string, offset shipOffset, stop <-chan struct{}) shipOffset {
+	if offset.Offset == 0 || sameFile(path, offset) {
+		return offset
+	}
+	names := RotatedFiles(path)
+	if len(names) > 0 && names[len(names)-1] == path {
+		names = names[:len(names)-1]
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		if !sameFile(names[i], offset) {
+			continue
+		}
+		if !s.shipRest(path, names[i], offset.Offset, stop) {
+			return offset
+		}
+		for _, name := range names[i+1:] {
+			if !s.shipRest(path, name, 0, stop) {
+				return offset
+			}
+		}
+		break
+	}
+	return shipOffset{}
+}
+
+// sameFile return true if name is the file of the checkpoint offset: same
+// first bytes and, if both are known, same device and inode. Compressed
+// files are new files, only their first bytes are compared.
+func sameFile(name string, offset shipOffset) bool {
+	if offset.Ino != 0 && !strings.HasSuffix(name, ".gz") {
+		if info, err := os.Stat(name); err == nil {
+			if dev, ino, ok := fileID(info); ok && (dev != offset.Dev || ino != offset.Ino) {
+				return false
+			}
+		}
+	}
+	sum, err := fileSum(name, offset.Offset)
+	return err == nil && sum == offset.Sum
+}
+
+// shipRest write the lines of a rotated file after offset.
+// It returns false if stop was closed before.
+func (s *Shipper) shipRest(path, name string, offset int64, stop <-chan struct{}) bool {
+	r, err := OpenLogFile(name)
+	if err != nil {
+		s.report(err)
+		return true
+	}
+	defer r.Close()
+	if _, err := io.CopyN(ioutil.Discard, r, offset); err != nil {
+		s.report(fmt.Errorf("%s: %w", name, err))
+		return true
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if !s.write(path, scanner.Bytes(), stop) {
+			return false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		s.report(fmt.Errorf("%s: %w", name, err))
+	}
+	return true
+}
+
+// fileSum return the checksum of the first bytes of name, up to offset
+func fileSum(name string, offset int64) (uint32, error) {
+	r, err := OpenLogFile(name)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return readSum(r, offset)
+}
+
+func readSum(r io.Reader, offset int64) (uint32, error) {
+	if offset > fingerprintSize {
+		offset = fingerprintSize
+	}
+	buf := make([]byte, offset)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return 0, err
+	}
+	return crc32.ChecksumIEEE(buf), nil
+}
+
+// follow write the lines of f until stop is closed
+func (s *Shipper) follow(f *shipFile, stop <-chan struct{}) {
+	for {
+		line, err := f.follower.Next(stop)
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			s.report(fmt.Errorf("%s: %w", f.follower.Path(), err))
+			select {
+			case <-stop:
+				return
+			case <-time.After(s.cfg.Backoff):
+			}
+			continue
+		}
+		if !s.write(f.follower.Path(), line, stop) {
+			return
+		}
+		s.update(f)
+	}
+}
+
+// update the checkpoint of f after a written line
+func (s *Shipper) update(f *shipFile) {
+	offset := shipOffset{Offset: f.follower.Offset()}
+	info := f.follower.Info()
+	file := f.follower.file
+
+	s.mutex.Lock()
+	prev, prevInfo := f.offset, f.info
+	s.mutex.Unlock()
+
+	if file == nil {
+		// rotated, the next line is read from the start of the new file
+		offset, info = shipOffset{}, nil
+	} else if prevInfo != nil && os.SameFile(info, prevInfo) && prev.Offset >= fingerprintSize && offset.Offset >= prev.Offset {
+		offset.Sum, offset.Dev, offset.Ino = prev.Sum, prev.Dev, prev.Ino
+	} else {
+		sum, err := readSum(io.NewSectionReader(file, 0, offset.Offset), offset.Offset)
+		if err != nil {
+			s.report(fmt.Errorf("%s: %w", f.follower.Path(), err))
+			return
+		}
+		offset.Sum = sum
+		offset.setID(info)
+	}
+
+	s.mutex.Lock()
+	f.offset, f.info = offset, info
+	s.mutex.Unlock()
+}
+
+// write decode line and write it into Out, retrying until it succeeds.
+// It returns false if stop was closed before.
+func (s *Shipper) write(path string, line []byte, stop <-chan struct{}) bool {
+	if len(line) == 0 {
+		return true
+	}
+	e, err := s.cfg.Decoder.Decode(line)
+	if err != nil {
+		s.report(fmt.Errorf("%s: %w", path, err))
+		return true
+	}
+	if e.Time.IsZero() {
+		e.Time = s.cfg.Clock.Now()
+	}
+	if s.cfg.PathKey != "" {
+		e.Fields = append(e.Fields, String(s.cfg.PathKey, path))
+	}
+	for {
+		err := WriteEntry(s.cfg.Out, e)
+		if err == nil {
+			return true
+		}
+		s.report(fmt.Errorf("%s: %w", path, err))
+		select {
+		case <-stop:
+			return false
+		case <-time.After(s.cfg.Backoff):
+		}
+	}
+}
+
+// checkpoint sync Out then save the offsets taken before, so every line
+// before a saved offset is written
+func (s *Shipper) checkpoint() error {
+	s.mutex.Lock()
+	offsets := make(map[string]shipOffset, len(s.files))
+	for path, f := range s.files {
+		offsets[path] = f.offset
+	}
+	s.mutex.Unlock()
+
+	if err := Sync(s.cfg.Out); err != nil {
+		return err
+	}
+	if s.cfg.Offsets == "" {
+		return nil
+	}
+	return writeOffsets(s.cfg.Offsets, offsets)
+}
+
+// writeOffsets replace the offsets file atomically
+func writeOffsets(name string, offsets map[string]shipOffset) error {
+	data, err := json.MarshalIndent(offsets, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
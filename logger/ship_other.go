@@ -0,0 +1,11 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package logger
+
+import "os"
+
+// fileID is not available, files are only identified by their first bytes
+func fileID(info os.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shipFor run a shipper of dir/*.log until want lines were written
+func shipFor(t *testing.T, dir string, want int) string {
+	t.Helper()
+	buf := &syncBuffer{}
+	s, err := NewShipper(ShipConfig{
+		Paths:      []string{filepath.Join(dir, "*.log")},
+		Out:        NewWriterSink(buf, &TextEncoder{Time: TimeFormat{Layout: TimeUnix}}),
+		Offsets:    filepath.Join(dir, "offsets.json"),
+		PathKey:    "file",
+		Poll:       time.Millisecond,
+		Checkpoint: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- s.Run(stop) }()
+	// run a little longer than needed to catch duplicates
+	start := time.Now()
+	for (strings.Count(buf.String(), "\n") < want || time.Since(start) < 20*time.Millisecond) &&
+		time.Since(start) < 2*time.Second {
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestShipperResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	ioutil.WriteFile(path, []byte("10 INFO one\n20 WARN two size=3\n"), 0644)
+
+	got := shipFor(t, dir, 2)
+	want := "10 INFO one file=" + path + "\n20 WARN two size=3 file=" + path + "\n"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// restart without new lines
+	if got := shipFor(t, dir, 0); got != "" {
+		t.Errorf("shipped again %q", got)
+	}
+
+	// lines appended then the file rotated while the shipper is stopped
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString("30 ERROR three\n")
+	file.Close()
+	os.Rename(path, path+"."+time.Unix(30, 0).Format(RotatedTimeFormat))
+	ioutil.WriteFile(path, []byte("40 INFO four\n"), 0644)
+
+	got = shipFor(t, dir, 2)
+	want = "30 ERROR three file=" + path + "\n40 INFO four file=" + path + "\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestShipperResumeRotatedTwice(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	header := "10 INFO started\n"
+	ioutil.WriteFile(path, []byte(header), 0644)
+	if got := shipFor(t, dir, 1); got != "10 INFO started file="+path+"\n" {
+		t.Fatalf("got %q", got)
+	}
+
+	// while stopped: a line appended, rotated, a new file with the same
+	// first line written and rotated too, then a third file
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	file.WriteString("20 INFO one\n")
+	file.Close()
+	os.Rename(path, path+"."+time.Unix(20, 0).Format(RotatedTimeFormat))
+	ioutil.WriteFile(path, []byte(header+"30 INFO two\n"), 0644)
+	os.Rename(path, path+"."+time.Unix(30, 0).Format(RotatedTimeFormat))
+	ioutil.WriteFile(path, []byte("40 INFO three\n"), 0644)
+
+	var want string
+	for _, line := range []string{"20 INFO one", "10 INFO started", "30 INFO two", "40 INFO three"} {
+		want += line + " file=" + path + "\n"
+	}
+	if info, _ := os.Stat(path); info != nil {
+		if _, _, ok := fileID(info); !ok {
+			// without inodes the newest file with the same first bytes is taken
+			want = "30 INFO two file=" + path + "\n40 INFO three file=" + path + "\n"
+		}
+	}
+	if got := shipFor(t, dir, strings.Count(want, "\n")); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestShipperPipe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ship")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	ioutil.WriteFile(path, []byte("10 INFO one\n"), 0644)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	// like gologs ship | cat, syncing the pipe must not stop checkpoints
+	offsets := filepath.Join(dir, "offsets.json")
+	s, err := NewShipper(ShipConfig{
+		Paths:      []string{path},
+		Out:        NewWriterSink(w, &TextEncoder{Time: TimeFormat{Layout: TimeUnix}}),
+		Offsets:    offsets,
+		Poll:       time.Millisecond,
+		Checkpoint: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() { done <- s.Run(stop) }()
+	select {
+	case line := <-lines:
+		if line != "10 INFO one" {
+			t.Errorf("got %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("nothing shipped")
+	}
+	for start := time.Now(); time.Since(start) < 2*time.Second; time.Sleep(time.Millisecond) {
+		if _, err := os.Stat(offsets); err == nil {
+			break
+		}
+	}
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(offsets); err != nil {
+		t.Error("offsets not saved:", err)
+	}
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxPooledBuffer is the capacity above which buffers are not kept
+const maxPooledBuffer = 64 << 10
+
+// bufPool keep buffers for encoding entries
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+// WriterSink is a Log writing encoded entries into a writer.
+// Besides the Log methods it has typed methods taking a message and fields:
+//
+//	sink.Info("packet forwarded", logger.String("stream_id", id), logger.Int("size", n))
+//
+// which do not allocate when the level is disabled, and encode into pooled
+// buffers without allocation for TextEncoder, LogfmtEncoder and JSONEncoder typed fields.
+type WriterSink struct {
+	leveled
+	*stackCounter
+	out   *levelOutput
+	enc   Encoder
+	level int32 // atomic Level
+	clock Clock // time of entries and stack ticker
+	mutex sync.Mutex
+}
+
+// NewWriterSink return a sink writing entries encoded by enc into out
+// Stack counters are written every LOG_INTERVAL seconds
+// Only the WithClock, WithLevel, WithLevelOutput and WithWriteError options are used
+func NewWriterSink(out io.Writer, enc Encoder, opts ...Option) *WriterSink {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	s := &WriterSink{
+		out:   newLevelOutput(out, o),
+		enc:   enc,
+		level: int32(o.level),
+		clock: o.clock,
+	}
+	s.leveled = s.log
+	s.stackCounter = newStackCounter(o.clock, s.WriteEntry)
+	return s
+}
+
+// Enabled return true if entries of level are written
+func (s *WriterSink) Enabled(level Level) bool {
+	return level >= Level(atomic.LoadInt32(&s.level))
+}
+
+// SetLevel set the min level written
+func (s *WriterSink) SetLevel(level Level) {
+	atomic.StoreInt32(&s.level, int32(level))
+}
+
+// Debug linter
+func (s *WriterSink) Debug(msg string, fields ...Field) {
+	if s.Enabled(DebugLevel) {
+		s.write(s.clock.Now(), DebugLevel, msg, fields)
+	}
+}
+
+// Info linter
+func (s *WriterSink) Info(msg string, fields ...Field) {
+	if s.Enabled(InfoLevel) {
+		s.write(s.clock.Now(), InfoLevel, msg, fields)
+	}
+}
+
+// Warn linter
+func (s *WriterSink) Warn(msg string, fields ...Field) {
+	if s.Enabled(WarnLevel) {
+		s.write(s.clock.Now(), WarnLevel, msg, fields)
+	}
+}
+
+// Error linter
+func (s *WriterSink) Error(msg string, fields ...Field) {
+	if s.Enabled(ErrorLevel) {
+		s.write(s.clock.Now(), ErrorLevel, msg, fields)
+	}
+}
+
+// LogEntry write e if its level is enabled
+func (s *WriterSink) LogEntry(e *Entry) {
+	if s.Enabled(e.Level) {
+		s.write(e.Time, e.Level, e.Message, e.Fields)
+	}
+}
+
+func (s *WriterSink) log(level Level, v ...interface{}) {
+	if !s.Enabled(level) {
+		return
+	}
+	e := NewEntry(level, v...)
+	s.write(s.clock.Now(), level, e.Message, e.Fields)
+}
+
+// Clock return the clock stamping entries
+func (s *WriterSink) Clock() Clock {
+	return s.clock
+}
+
+// Sync write pending stack counters and commit the outputs if they are files
+func (s *WriterSink) Sync() error {
+	s.dumpStacks()
+	return s.out.Sync()
+}
+
+// WriteEntry encode and write an entry
+func (s *WriterSink) WriteEntry(e *Entry) error {
+	return s.write(e.Time, e.Level, e.Message, e.Fields)
+}
+
+// write encode an entry into a pooled buffer and write it.
+// fields must not be kept, they may live on the caller stack
+func (s *WriterSink) write(now time.Time, level Level, msg string, fields []Field) error {
+	bp := bufPool.Get().(*[]byte)
+	buf := (*bp)[:0]
+
+	var err error
+	switch enc := s.enc.(type) {
+	case *JSONEncoder:
+		buf = enc.appendEntry(buf, now, level, msg, fields)
+	case *TextEncoder:
+		buf = enc.appendEntry(buf, now, level, msg, fields)
+	case *LogfmtEncoder:
+		buf = enc.appendEntry(buf, now, level, msg, fields)
+	default:
+		e := &Entry{Time: now, Level: level, Message: msg, Fields: append([]Field(nil), fields...)}
+		var b []byte
+		b, err = s.enc.Encode(e)
+		buf = append(buf, b...)
+	}
+	if err == nil {
+		s.mutex.Lock()
+		err = s.out.write(level, buf)
+		s.mutex.Unlock()
+	}
+
+	if cap(buf) <= maxPooledBuffer {
+		*bp = buf
+		bufPool.Put(bp)
+	}
+	return err
+}
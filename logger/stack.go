@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// stackCounter implement STACK for sinks: ids are counted and written
+// every LOG_INTERVAL seconds as one INFO entry "stack" with a field per id
+type stackCounter struct {
+	write    func(e *Entry) error
+	clock    Clock
+	stacks   *AdvanceMap // save stack counters
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newStackCounter(clock Clock, write func(e *Entry) error) *stackCounter {
+	s := &stackCounter{
+		write:  write,
+		clock:  clock,
+		stacks: NewAdvanceMap(),
+		stop:   make(chan struct{}),
+	}
+	go s.serve()
+	return s
+}
+
+// STACK increase counter of each id
+func (s *stackCounter) STACK(values ...string) {
+	for _, id := range values {
+		count := 0
+		if t, ok := s.stacks.Get(id); ok {
+			count, _ = t.(int)
+		}
+		s.stacks.Set(id, count+1)
+	}
+}
+
+// dumpStacks write and reset counters as one stamped entry with a field per id
+func (s *stackCounter) dumpStacks() {
+	tmp := s.stacks.Capture()
+	if len(tmp) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(tmp))
+	for k := range tmp {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	e := NewEntry(InfoLevel, "stack")
+	e.Time = s.clock.Now()
+	for _, k := range keys {
+		e.Fields = append(e.Fields, Any(k, tmp[k]))
+	}
+	// written by the sink, behind any Sequencer
+	e.Fields = StampFields(e.Fields)
+	s.write(e)
+}
+
+// serve write stacking
+func (s *stackCounter) serve() {
+	ticker := s.clock.NewTicker(time.Duration(getInterval()) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			s.dumpStacks()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Stop write pending stack counters and stop the stack ticker
+func (s *stackCounter) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		s.dumpStacks()
+	})
+}
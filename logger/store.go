@@ -0,0 +1,367 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entryOverhead is the size counted for an entry besides its text
+const entryOverhead = 64
+
+// StoreQuery select stored entries, zero values match everything
+type StoreQuery struct {
+	Level    Level             // min level
+	Since    time.Time         // entries at or after
+	Until    time.Time         // entries before
+	Fields   map[string]string // field key - text value
+	Where    []FieldExpr       // field expressions, all must match
+	Contains string            // substring of message or field values
+	Regexp   *regexp.Regexp    // match message or field values
+	Limit    int               // max entries, the newest are kept
+}
+
+// ParseStoreQuery read a query from url values:
+//
+//	level=warn&since=2020-09-10T01:00:00Z&until=1599700000&field=stream_id:abc&q=closed&limit=100
+//
+// since and until are RFC3339 or unix seconds, field and where can be repeated,
+// where is a field expression (see ParseFieldExpr) and regexp match the entry text
+func ParseStoreQuery(values url.Values) (StoreQuery, error) {
+	var q StoreQuery
+	var err error
+	if level := values.Get("level"); level != "" {
+		if q.Level, err = ParseLevel(level); err != nil {
+			return q, err
+		}
+	}
+	if q.Since, err = parseQueryTime(values.Get("since")); err != nil {
+		return q, err
+	}
+	if q.Until, err = parseQueryTime(values.Get("until")); err != nil {
+		return q, err
+	}
+	for _, field := range values["field"] {
+		i := strings.IndexByte(field, ':')
+		if i <= 0 {
+			return q, fmt.Errorf("field %q is not key:value", field)
+		}
+		if q.Fields == nil {
+			q.Fields = make(map[string]string)
+		}
+		q.Fields[field[:i]] = field[i+1:]
+	}
+	for _, where := range values["where"] {
+		x, err := ParseFieldExpr(where)
+		if err != nil {
+			return q, err
+		}
+		q.Where = append(q.Where, x)
+	}
+	if expr := values.Get("regexp"); expr != "" {
+		if q.Regexp, err = regexp.Compile(expr); err != nil {
+			return q, err
+		}
+	}
+	q.Contains = values.Get("q")
+	if limit := values.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			return q, fmt.Errorf("invalid limit %q", limit)
+		}
+	}
+	return q, nil
+}
+
+// parseQueryTime parse RFC3339 or unix seconds, empty is the zero time
+func parseQueryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q", s)
+	}
+	return t, nil
+}
+
+// Match check e is selected by the query, Limit is not used
+func (q *StoreQuery) Match(e *Entry) bool {
+	if e.Level < q.Level {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	for key, want := range q.Fields {
+		value, ok := e.Field(key)
+		if !ok || fmt.Sprint(value) != want {
+			return false
+		}
+	}
+	for i := range q.Where {
+		if !q.Where[i].Match(e) {
+			return false
+		}
+	}
+	if q.Contains == "" && q.Regexp == nil {
+		return true
+	}
+	text := e.Text()
+	return (q.Contains == "" || strings.Contains(text, q.Contains)) &&
+		(q.Regexp == nil || q.Regexp.MatchString(text))
+}
+
+// storedEntry is an entry with its store id
+type storedEntry struct {
+	id    uint64
+	entry *Entry
+	size  int
+}
+
+// storeSubscriber receive new entries matching its query
+type storeSubscriber struct {
+	query   StoreQuery
+	entries chan *storedEntry
+}
+
+// Store keep the last entries up to a size in bytes, entries can be
+// queried and followed, see Handler for the HTTP API
+type Store struct {
+	maxBytes    int
+	bytes       int
+	entries     []*storedEntry // oldest first
+	lastID      uint64
+	subscribers map[*storeSubscriber]bool
+	mutex       sync.RWMutex
+	notify      sync.Mutex // keep entries of subscribers in id order
+}
+
+// NewStore return a store of at most maxBytes of entries, the size of an
+// entry is its text plus a fixed overhead
+func NewStore(maxBytes int) *Store {
+	return &Store{
+		maxBytes:    maxBytes,
+		subscribers: make(map[*storeSubscriber]bool),
+	}
+}
+
+// Hook add a copy of e, use it as a HookFunc
+func (s *Store) Hook(e *Entry) error {
+	clone := *e
+	clone.Fields = append([]Field(nil), e.Fields...)
+	s.Add(&clone)
+	return nil
+}
+
+// Add keep e, which must not be modified after, and evict the oldest entries above the size
+func (s *Store) Add(e *Entry) {
+	size := entryOverhead + len(e.Message)
+	for _, field := range e.Fields {
+		size += len(field.Key) + len(appendTextValue(nil, field))
+	}
+
+	s.mutex.Lock()
+	s.lastID++
+	stored := &storedEntry{id: s.lastID, entry: e, size: size}
+	s.entries = append(s.entries, stored)
+	s.bytes += size
+
+	evict := 0
+	for s.bytes > s.maxBytes && evict < len(s.entries) {
+		s.bytes -= s.entries[evict].size
+		s.entries[evict] = nil
+		evict++
+	}
+	s.entries = s.entries[evict:]
+
+	if len(s.subscribers) == 0 {
+		s.mutex.Unlock()
+		return
+	}
+	subscribers := make([]*storeSubscriber, 0, len(s.subscribers))
+	for sub := range s.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	// match outside the store lock, the notify lock is taken first so
+	// entries are sent in id order
+	s.notify.Lock()
+	s.mutex.Unlock()
+	defer s.notify.Unlock()
+
+	for _, sub := range subscribers {
+		if sub.query.Match(e) {
+			// a slow subscriber miss entries instead of blocking the log
+			select {
+			case sub.entries <- stored:
+			default:
+			}
+		}
+	}
+}
+
+// Len return number of stored entries and their size
+func (s *Store) Len() (int, int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.entries), s.bytes
+}
+
+// Query return matching entries from oldest to newest
+func (s *Store) Query(q StoreQuery) []*Entry {
+	stored := s.query(q, 0)
+	result := make([]*Entry, len(stored))
+	for i, st := range stored {
+		result[i] = st.entry
+	}
+	return result
+}
+
+// query return matching entries with an id above after
+func (s *Store) query(q StoreQuery, after uint64) []*storedEntry {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var result []*storedEntry
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		st := s.entries[i]
+		if st.id <= after || (q.Limit > 0 && len(result) >= q.Limit) {
+			break
+		}
+		if q.Match(st.entry) {
+			result = append(result, st)
+		}
+	}
+	// newest were found first
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// subscribe return a subscriber of new entries matching q
+func (s *Store) subscribe(q StoreQuery, buffer int) *storeSubscriber {
+	sub := &storeSubscriber{query: q, entries: make(chan *storedEntry, buffer)}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.subscribers[sub] = true
+	return sub
+}
+
+func (s *Store) unsubscribe(sub *storeSubscriber) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.subscribers, sub)
+}
+
+// Handler return the HTTP API of the store, query parameters are read by ParseStoreQuery
+//
+//	GET /entries  matching entries as a JSON array, oldest first
+//	GET /tail     new matching entries as Server-Sent Events, the event id is
+//	              the store id so a client reconnecting with Last-Event-ID
+//	              receive the entries it missed
+//
+// Entries are encoded by the JSONEncoder
+func (s *Store) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/entries", s.serveEntries)
+	mux.HandleFunc("/tail", s.serveTail)
+	return mux
+}
+
+// storeEncoder encode entries of the HTTP API
+var storeEncoder = &JSONEncoder{}
+
+func (s *Store) serveEntries(w http.ResponseWriter, r *http.Request) {
+	q, err := ParseStoreQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	buf := []byte{'['}
+	for i, e := range s.Query(q) {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		line, _ := storeEncoder.Encode(e)
+		buf = append(buf, line[:len(line)-1]...)
+	}
+	buf = append(buf, ']', '\n')
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf)
+}
+
+// sseKeepAlive is the interval of comments keeping idle streams open
+var sseKeepAlive = 15 * time.Second
+
+func (s *Store) serveTail(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	q, err := ParseStoreQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := q.Limit
+	q.Limit = 0
+
+	// subscribe before replaying so no entry falls in between
+	sub := s.subscribe(q, 256)
+	defer s.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	var last uint64
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		after, _ := strconv.ParseUint(id, 10, 64)
+		replay := q
+		replay.Limit = limit
+		for _, st := range s.query(replay, after) {
+			writeEvent(w, st)
+			last = st.id
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case st := <-sub.entries:
+			if st.id <= last {
+				continue
+			}
+			writeEvent(w, st)
+			flusher.Flush()
+		case <-ticker.C:
+			w.Write([]byte(": keep-alive\n\n"))
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent write an entry as a Server-Sent Event
+func writeEvent(w http.ResponseWriter, st *storedEntry) {
+	line, _ := storeEncoder.Encode(st.entry)
+	buf := append([]byte("id: "), strconv.FormatUint(st.id, 10)...)
+	buf = append(buf, "\ndata: "...)
+	buf = append(buf, line[:len(line)-1]...)
+	buf = append(buf, '\n', '\n')
+	w.Write(buf)
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func storeEntry(sec int64, level Level, v ...interface{}) *Entry {
+	e := NewEntry(level, v...)
+	e.Time = time.Unix(sec, 0)
+	return e
+}
+
+func TestStoreQuery(t *testing.T) {
+	s := NewStore(1 << 20)
+	s.Add(storeEntry(10, InfoLevel, "stream opened", String("stream_id", "a")))
+	s.Add(storeEntry(20, ErrorLevel, "stream closed", String("stream_id", "a"), Int("size", 3)))
+	s.Add(storeEntry(30, WarnLevel, "stream closed", String("stream_id", "b")))
+	s.Add(storeEntry(40, DebugLevel, "ping"))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"stream opened", "stream closed", "stream closed", "ping"}},
+		{"level=warn", []string{"stream closed", "stream closed"}},
+		{"since=20&until=40", []string{"stream closed", "stream closed"}},
+		{"field=stream_id:a&field=size:3", []string{"stream closed"}},
+		{"q=opened", []string{"stream opened"}},
+		{"q=stream_id=b", []string{"stream closed"}},
+		{"limit=2", []string{"stream closed", "ping"}},
+		{"where=size>=3", []string{"stream closed"}},
+		{"where=stream_id!=a", []string{"stream closed", "ping"}},
+		{"where=stream_id~^[ab]$&level=warn", []string{"stream closed", "stream closed"}},
+		{"regexp=^stream (opened|closed) stream_id=a", []string{"stream opened", "stream closed"}},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		q, err := ParseStoreQuery(values)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		var got []string
+		for _, e := range s.Query(q) {
+			got = append(got, e.Message)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"level=loud", "since=yesterday", "field=nokey", "limit=x", "where=nop", "regexp=("} {
+		values, _ := url.ParseQuery(query)
+		if _, err := ParseStoreQuery(values); err == nil {
+			t.Errorf("%q: expected an error", query)
+		}
+	}
+}
+
+func TestStoreEvict(t *testing.T) {
+	s := NewStore(3 * (entryOverhead + 10))
+	for i := 0; i < 5; i++ {
+		s.Add(storeEntry(int64(i), InfoLevel, "0123456789"))
+	}
+	if n, size := s.Len(); n != 3 || size != 3*(entryOverhead+10) {
+		t.Fatalf("len %d size %d", n, size)
+	}
+	if got := s.Query(StoreQuery{}); got[0].Time.Unix() != 2 {
+		t.Errorf("oldest kept entry %v", got[0].Time)
+	}
+}
+
+func TestStoreHTTP(t *testing.T) {
+	s := NewStore(1 << 20)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	s.Add(storeEntry(10, InfoLevel, "first", String("stream_id", "a")))
+	s.Add(storeEntry(20, ErrorLevel, "second", String("stream_id", "a")))
+
+	resp, err := http.Get(server.URL + "/entries?level=error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&entries)
+	resp.Body.Close()
+	if err != nil || len(entries) != 1 || entries[0]["msg"] != "second" || entries[0]["stream_id"] != "a" {
+		t.Fatalf("entries %v %v", entries, err)
+	}
+
+	if resp, _ = http.Get(server.URL + "/entries?level=loud"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", resp.StatusCode)
+	}
+
+	// replay after id 1 then follow
+	req, _ := http.NewRequest("GET", server.URL+"/tail?field=stream_id:a", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type %q", ct)
+	}
+	s.Add(storeEntry(30, InfoLevel, "other", String("stream_id", "b")))
+	s.Add(storeEntry(40, InfoLevel, "third\n[ERROR] forged", String("stream_id", "a")))
+
+	r := bufio.NewReader(resp.Body)
+	for _, want := range []string{"id: 2", `"msg":"second"`, "", "id: 4", `"msg":"third\n[ERROR] forged"`, ""} {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line = strings.TrimSuffix(line, "\n"); !strings.Contains(line, want) || (want == "" && line != "") {
+			t.Fatalf("line %q, want %q", line, want)
+		}
+	}
+}
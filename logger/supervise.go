@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// GoroutineKey is the field key of the goroutine name in panic entries
+const GoroutineKey = "goroutine"
+
+// Supervisor run named goroutines recovering their panics.
+// A panic is logged as an ERROR with the goroutine name and stack trace,
+// counted by Panics and as STACK id "panic:<name>".
+type Supervisor struct {
+	log        Log
+	backoff    time.Duration // first restart delay
+	maxBackoff time.Duration // restart delay limit
+	panics     *AdvanceMap   // name - recovered panics
+	clock      Clock         // measure run time and restart delays
+	stop       chan struct{}
+	stopOnce   sync.Once
+	mutex      sync.Mutex // increment of panics
+}
+
+// NewSupervisor return a supervisor logging panics into log
+// Restart delay start at backoff and double at each panic up to maxBackoff
+// Only the WithClock option is used
+func NewSupervisor(log Log, backoff, maxBackoff time.Duration, opts ...Option) *Supervisor {
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &Supervisor{
+		log:        log,
+		backoff:    backoff,
+		maxBackoff: maxBackoff,
+		panics:     NewAdvanceMap(),
+		clock:      o.clock,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Go run fn in a new goroutine, a panic is recovered and logged, fn is not restarted
+func (s *Supervisor) Go(name string, fn func()) {
+	go s.run(name, fn)
+}
+
+// Supervise run fn in a new goroutine and run it again after a panic.
+// It stops when fn returns or the supervisor is stopped, the delay is
+// reset when fn ran longer than maxBackoff before panicking.
+func (s *Supervisor) Supervise(name string, fn func()) {
+	go func() {
+		delay := s.backoff
+		for {
+			start := s.clock.Now()
+			if !s.run(name, fn) {
+				return
+			}
+			if s.clock.Now().Sub(start) > s.maxBackoff {
+				delay = s.backoff
+			}
+			if !s.wait(delay) {
+				return
+			}
+			if delay *= 2; delay > s.maxBackoff {
+				delay = s.maxBackoff
+			}
+		}
+	}()
+}
+
+// wait delay on the clock, return false if the supervisor is stopped first
+func (s *Supervisor) wait(delay time.Duration) bool {
+	if delay <= 0 {
+		select {
+		case <-s.stop:
+			return false
+		default:
+			return true
+		}
+	}
+	ticker := s.clock.NewTicker(delay)
+	defer ticker.Stop()
+	select {
+	case <-ticker.C():
+		return true
+	case <-s.stop:
+		return false
+	}
+}
+
+// run call fn and return true if it panicked
+func (s *Supervisor) run(name string, fn func()) (panicked bool) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicked = true
+			s.recovered(name, value)
+		}
+	}()
+	fn()
+	return false
+}
+
+// recovered count and log a panic of name
+func (s *Supervisor) recovered(name string, value interface{}) {
+	s.mutex.Lock()
+	count := s.Panics(name) + 1
+	s.panics.Set(name, count)
+	s.mutex.Unlock()
+
+	s.log.STACK("panic:" + name)
+	s.log.ERROR(fmt.Sprintf("[%s] panic: %v\n%s", name, value, debug.Stack()), String(GoroutineKey, name), Int("panics", count))
+}
+
+// Panics return number of recovered panics of name
+func (s *Supervisor) Panics(name string) int {
+	if t, ok := s.panics.Get(name); ok {
+		if count, ok := t.(int); ok {
+			return count
+		}
+	}
+	return 0
+}
+
+// Stop cancel pending restarts, running goroutines are not interrupted
+func (s *Supervisor) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a buffer safe to read while a goroutine writes
+type syncBuffer struct {
+	buf   bytes.Buffer
+	mutex sync.Mutex
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.buf.String()
+}
+
+func TestSupervisorRestart(t *testing.T) {
+	buf := &syncBuffer{}
+	s := NewSupervisor(NewWriterSink(buf, &TextEncoder{}), time.Millisecond, 4*time.Millisecond)
+	defer s.Stop()
+
+	var runs int32
+	done := make(chan struct{})
+	s.Supervise("job", func() {
+		if atomic.AddInt32(&runs, 1) <= 3 {
+			panic("boom")
+		}
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not restarted")
+	}
+	if n := s.Panics("job"); n != 3 {
+		t.Errorf("panics = %d, want 3", n)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "[job] panic: boom") || !strings.Contains(out, "supervise_test.go") || !strings.Contains(out, "goroutine=job panics=3") {
+		t.Errorf("unexpected output %q", out)
+	}
+}
+
+func TestSupervisorGo(t *testing.T) {
+	buf := &syncBuffer{}
+	s := NewSupervisor(NewWriterSink(buf, &TextEncoder{}), time.Millisecond, time.Millisecond)
+
+	var runs int32
+	s.Go("once", func() {
+		atomic.AddInt32(&runs, 1)
+		panic("boom")
+	})
+	for i := 0; i < 500 && s.Panics("once") == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 1 || s.Panics("once") != 1 {
+		t.Errorf("runs = %d panics = %d, want 1 and 1", n, s.Panics("once"))
+	}
+}
+
+func TestSupervisorClock(t *testing.T) {
+	buf := &syncBuffer{}
+	clock := NewManualClock(time.Unix(100, 0))
+	s := NewSupervisor(NewWriterSink(buf, &TextEncoder{}), time.Minute, time.Hour, WithClock(clock))
+	defer s.Stop()
+
+	var runs int32
+	s.Supervise("job", func() {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			panic("boom")
+		}
+	})
+	for i := 0; i < 500 && s.Panics("job") == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Fatalf("restarted before the clock moved, runs = %d", n)
+	}
+
+	// the restart ticker may not exist yet, keep moving the clock
+	for i := 0; i < 500 && atomic.LoadInt32(&runs) < 2; i++ {
+		clock.Add(time.Minute)
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&runs); n != 2 {
+		t.Errorf("runs = %d after the backoff, want 2", n)
+	}
+}
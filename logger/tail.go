@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TailSampler keep DEBUG entries of each correlation id in a bounded buffer.
+// The buffer of an id is written before its first ERROR, or by Flush,
+// and discarded by Discard or when the id was idle longer than ttl.
+type TailSampler struct {
+	next     Log
+	size     int           // max entries per id
+	maxIDs   int           // max ids buffering at the same time
+	ttl      time.Duration // idle time before a buffer is discarded
+	buffers  map[string]*tailBuffer
+	stop     chan struct{}
+	stopOnce sync.Once
+	mutex    sync.Mutex
+}
+
+type tailBuffer struct {
+	entries []*Entry
+	updated time.Time
+}
+
+// NewTailSampler return a tail sampler writing to next
+func NewTailSampler(next Log, size, maxIDs int, ttl time.Duration) *TailSampler {
+	t := &TailSampler{
+		next:    next,
+		size:    size,
+		maxIDs:  maxIDs,
+		ttl:     ttl,
+		buffers: make(map[string]*tailBuffer),
+		stop:    make(chan struct{}),
+	}
+	go t.serve()
+	return t
+}
+
+// Log write an entry of ctx.
+// DEBUG entries with a correlation id are buffered, other entries are
+// written with the correlation id field, an ERROR flush the buffer first.
+func (t *TailSampler) Log(ctx context.Context, level Level, v ...interface{}) {
+	v = ContextArgs(ctx, v)
+
+	id := CorrelationID(ctx)
+	if id == "" {
+		Emit(t.next, level, v...)
+		return
+	}
+
+	switch {
+	case level == DebugLevel:
+		t.buffer(id, NewEntry(level, v...))
+	case level >= ErrorLevel:
+		t.Flush(id)
+		Emit(t.next, level, v...)
+	default:
+		Emit(t.next, level, v...)
+	}
+}
+
+// buffer add an entry to buffer of id, drop the oldest if full
+func (t *TailSampler) buffer(id string, e *Entry) {
+	if t.size <= 0 {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	b, ok := t.buffers[id]
+	if !ok {
+		if t.maxIDs > 0 && len(t.buffers) >= t.maxIDs {
+			t.evictOldest()
+		}
+		b = &tailBuffer{}
+		t.buffers[id] = b
+	}
+	if len(b.entries) >= t.size {
+		b.entries = b.entries[1:]
+	}
+	b.entries = append(b.entries, e)
+	b.updated = time.Now()
+}
+
+// evictOldest discard the least recently updated buffer
+func (t *TailSampler) evictOldest() {
+	var oldest string
+	var updated time.Time
+	for id, b := range t.buffers {
+		if oldest == "" || b.updated.Before(updated) {
+			oldest = id
+			updated = b.updated
+		}
+	}
+	delete(t.buffers, oldest)
+}
+
+// take remove and return buffered entries of id
+func (t *TailSampler) take(id string) []*Entry {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	b, ok := t.buffers[id]
+	if !ok {
+		return nil
+	}
+	delete(t.buffers, id)
+	return b.entries
+}
+
+// Flush write buffered entries of id
+func (t *TailSampler) Flush(id string) {
+	for _, e := range t.take(id) {
+		WriteEntry(t.next, e)
+	}
+}
+
+// Discard drop buffered entries of id
+func (t *TailSampler) Discard(id string) {
+	t.take(id)
+}
+
+// Len return number of ids buffering
+func (t *TailSampler) Len() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.buffers)
+}
+
+// Stop the expiration loop
+func (t *TailSampler) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+}
+
+// expire discard buffers idle longer than ttl at now
+func (t *TailSampler) expire(now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for id, b := range t.buffers {
+		if now.Sub(b.updated) > t.ttl {
+			delete(t.buffers, id)
+		}
+	}
+}
+
+// serve expire buffers every ttl
+func (t *TailSampler) serve() {
+	if t.ttl <= 0 {
+		return
+	}
+	ticker := time.NewTicker(t.ttl)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			t.expire(now)
+		case <-t.stop:
+			return
+		}
+	}
+}
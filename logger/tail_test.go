@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailSamplerFlushOnError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tail := NewTailSampler(newTestLog(buf), 2, 10, time.Hour)
+	defer tail.Stop()
+
+	ok := WithCorrelationID(context.Background(), "ok")
+	failed := WithCorrelationID(context.Background(), "failed")
+
+	tail.Log(ok, DebugLevel, "ok debug")
+	tail.Log(failed, DebugLevel, "debug 1")
+	tail.Log(failed, DebugLevel, "debug 2")
+	tail.Log(failed, DebugLevel, "debug 3")
+	if buf.Len() != 0 {
+		t.Fatalf("debug should be buffered, got %q", buf.String())
+	}
+
+	tail.Log(failed, ErrorLevel, "handler err")
+	want := "DEBUG debug 2 correlation_id=failed\n" +
+		"DEBUG debug 3 correlation_id=failed\n" +
+		"ERROR handler err correlation_id=failed\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	tail.Discard("ok")
+	tail.Flush("ok")
+	if buf.Len() != 0 || tail.Len() != 0 {
+		t.Fatalf("discarded buffer should not be written, got %q", buf.String())
+	}
+}
+
+func TestTailSamplerBounds(t *testing.T) {
+	buf := &bytes.Buffer{}
+	tail := NewTailSampler(newTestLog(buf), 10, 2, time.Hour)
+	defer tail.Stop()
+
+	for _, id := range []string{"a", "b", "c"} {
+		tail.Log(WithCorrelationID(context.Background(), id), DebugLevel, id)
+	}
+	if tail.Len() != 2 {
+		t.Fatalf("expect 2 buffers, got %d", tail.Len())
+	}
+	tail.Flush("a")
+	if buf.Len() != 0 {
+		t.Fatalf("oldest buffer should be evicted, got %q", buf.String())
+	}
+
+	tail.expire(time.Now().Add(2 * time.Hour))
+	if tail.Len() != 0 {
+		t.Fatalf("expect expired buffers, got %d", tail.Len())
+	}
+
+	tail.Log(context.Background(), DebugLevel, "no id")
+	if !strings.Contains(buf.String(), "DEBUG no id") {
+		t.Fatalf("entry without id should be written, got %q", buf.String())
+	}
+}
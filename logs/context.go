@@ -0,0 +1,69 @@
+package logs
+
+import (
+	"context"
+
+	"github.com/lamhai1401/gologs/logger"
+)
+
+// ErrorCtx export error log of ctx
+// Buffered debug logs of the correlation id in ctx are written before it
+func ErrorCtx(ctx context.Context, v ...interface{}) {
+	if OffLog != "1" {
+		t := getPipeline("").tail
+		async(func(v ...interface{}) { t.Log(ctx, logger.ErrorLevel, v...) }, withCaller(v, 1))
+	}
+}
+
+// InfoCtx export none error log of ctx
+func InfoCtx(ctx context.Context, v ...interface{}) {
+	if OffLog != "1" {
+		t := getPipeline("").tail
+		async(func(v ...interface{}) { t.Log(ctx, logger.InfoLevel, v...) }, v)
+	}
+}
+
+// WarnCtx export none error log of ctx
+func WarnCtx(ctx context.Context, v ...interface{}) {
+	if OffLog != "1" {
+		t := getPipeline("").tail
+		async(func(v ...interface{}) { t.Log(ctx, logger.WarnLevel, v...) }, v)
+	}
+}
+
+// DebugCtx buffer debug log of the correlation id in ctx.
+// It is written only if the id logs an error later or is flushed,
+// DEBUG=1 write it right away.
+func DebugCtx(ctx context.Context, v ...interface{}) {
+	if OffLog == "1" {
+		return
+	}
+	p := getPipeline("")
+	if getLevel() == logger.DebugLevel {
+		async(p.head.DEBUG, logger.ContextArgs(ctx, v))
+		return
+	}
+	if logger.CorrelationID(ctx) == "" {
+		// nothing to buffer, the entry goes down the chain
+		t := p.tail
+		async(func(v ...interface{}) { t.Log(ctx, logger.DebugLevel, v...) }, v)
+		return
+	}
+	// buffer in caller goroutine to keep order with a following error
+	p.tail.Log(ctx, logger.DebugLevel, v...)
+}
+
+// Flush write buffered debug logs of the correlation id in ctx
+func Flush(ctx context.Context) {
+	if id := logger.CorrelationID(ctx); id != "" {
+		getPipeline("").tail.Flush(id)
+	}
+}
+
+// Discard drop buffered debug logs of the correlation id in ctx,
+// call it when a request or stream ends without failure
+func Discard(ctx context.Context) {
+	if id := logger.CorrelationID(ctx); id != "" {
+		getPipeline("").tail.Discard(id)
+	}
+}
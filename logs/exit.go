@@ -0,0 +1,115 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lamhai1401/gologs/logger"
+)
+
+// pending count entries still written by log goroutines
+var pending int64
+
+// exitHooks run by Exit after the log is flushed
+var exitHooks []func()
+var exitMutex sync.Mutex
+
+// exit is os.Exit, replaced by tests
+var exit = os.Exit
+
+// async write v with fn in a new goroutine, Exit wait for it
+func async(fn func(v ...interface{}), v []interface{}) {
+	atomic.AddInt64(&pending, 1)
+	go func() {
+		defer atomic.AddInt64(&pending, -1)
+		fn(v...)
+	}()
+}
+
+// asyncEntry log e into l in a new goroutine, Exit wait for it
+func asyncEntry(l logger.Log, e *logger.Entry) {
+	atomic.AddInt64(&pending, 1)
+	go func() {
+		defer atomic.AddInt64(&pending, -1)
+		logger.LogEntry(l, e)
+	}()
+}
+
+// waitPending wait log goroutines and late hooks for at most
+// LOG_EXIT_TIMEOUT milliseconds (default 5000)
+func waitPending() {
+	deadline := time.Now().Add(time.Duration(getEnvInt("LOG_EXIT_TIMEOUT", 5000)) * time.Millisecond)
+	for (atomic.LoadInt64(&pending) > 0 || hooks.Abandoned() > 0) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// flush wait pending entries, write sampler summaries of every pipeline
+// then sync Log, it waits for the batches of fluent and otlp backends
+func flush() {
+	waitPending()
+	for _, key := range pipelines.GetKeys() {
+		if t, ok := pipelines.Get(key); ok {
+			if p, ok := t.(*pipeline); ok && p.sampler != nil {
+				p.sampler.Flush()
+			}
+		}
+	}
+	if err := logger.Sync(Log); err != nil {
+		fmt.Fprintln(os.Stderr, "log sync:", err)
+	}
+}
+
+// OnExit register fn to run by Fatal and Exit, hooks run in order
+// after the log is flushed
+func OnExit(fn func()) {
+	exitMutex.Lock()
+	defer exitMutex.Unlock()
+	exitHooks = append(exitHooks, fn)
+}
+
+// Exit wait entries being written, flush the pipelines and Log, run exit hooks then exit with code
+func Exit(code int) {
+	flush()
+
+	exitMutex.Lock()
+	hooks := append([]func(){}, exitHooks...)
+	exitMutex.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+	exit(code)
+}
+
+// Fatal write an entry with the caller location then Exit(1)
+// The entry is written in the caller goroutine after the ones being written
+func Fatal(v ...interface{}) {
+	fatal("", withCaller(v, 1))
+}
+
+// Panic write an entry with the caller location after the ones being written,
+// flush the pipelines and Log then panic with its message
+func Panic(v ...interface{}) {
+	logPanic("", v, withCaller(v, 1))
+}
+
+func fatal(name string, args []interface{}) {
+	waitPending()
+	if OffLog != "1" {
+		getPipeline(name).head.FATAL(args...)
+	}
+	Exit(1)
+}
+
+// logPanic write args of the panic value v
+func logPanic(name string, v, args []interface{}) {
+	if OffLog != "1" {
+		waitPending()
+		getPipeline(name).head.PANIC(args...)
+		flush()
+	}
+	panic(logger.NewEntry(logger.PanicLevel, v...).Message)
+}
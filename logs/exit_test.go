@@ -0,0 +1,88 @@
+package logs
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// withTestLog write through a Logging into out until the returned func is called
+func withTestLog(out *syncBuffer) func() {
+	old := backend
+	SetLogger(NewLogging(out, "", 0))
+	return func() {
+		SetLogger(old)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+
+	code := -1
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+
+	var atHook string
+	exitHooks = nil
+	OnExit(func() { atHook = out.String() })
+	defer func() { exitHooks = nil }()
+
+	Info("before")
+	Fatal("boom")
+
+	if code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	before, fatal := strings.Index(atHook, "[INFO] before"), strings.Index(atHook, "[FATAL] boom")
+	if before < 0 || fatal < before {
+		t.Errorf("hook should run after pending and fatal entries, got %q", atHook)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+
+	defer func() {
+		if value := recover(); value != "bad state 1" {
+			t.Errorf("panic value %v", value)
+		}
+		if !strings.Contains(out.String(), "[PANIC] bad state 1") {
+			t.Errorf("panic should be logged first, got %q", out.String())
+		}
+	}()
+	Named("fwd").PANIC("bad state", 1)
+}
+
+func TestPanicAfterPending(t *testing.T) {
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+
+	defer func() {
+		recover()
+		before, panicked := strings.Index(out.String(), "[INFO] before"), strings.Index(out.String(), "[PANIC] boom")
+		if before < 0 || panicked < before {
+			t.Errorf("pending entries should be written before the panic, got %q", out.String())
+		}
+	}()
+	Info("before")
+	Panic("boom")
+}
+
+func TestExitFlushSampler(t *testing.T) {
+	os.Setenv("LOG_SAMPLE_FIRST", "1")
+	defer os.Unsetenv("LOG_SAMPLE_FIRST")
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+
+	exit = func(int) {}
+	defer func() { exit = os.Exit }()
+
+	Info("same")
+	Info("same")
+	Exit(0)
+	if !strings.Contains(out.String(), "suppressed 1 similar messages: same") {
+		t.Errorf("exit should write sampler summaries, got %q", out.String())
+	}
+}
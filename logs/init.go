@@ -1,46 +1,241 @@
 package logs
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/lamhai1401/gologs/logger"
 )
 
 // Log linter
+// Entries written to it are redacted like the ones of named logs
 var Log logger.Log
 var OffLog string
 
+// backend is the log behind Log and every pipeline, without redaction.
+// It stamps entries with a sequence number and the session id as they are written.
+var backend logger.Log
+
 func init() {
-	Log = logger.NewFactorLog()
 	// logging = newLogger()
 	OffLog = os.Getenv("OFF_LOG")
+	redaction = redactionFromEnv()
+	setBackend(newBackend())
+	// hooks of an entry run together for LOG_HOOK_TIMEOUT milliseconds (default 1000),
+	// they are skipped while LOG_HOOK_MAX_ABANDONED late hooks still run (default 100)
+	hooks = logger.NewHooks(time.Duration(getEnvInt("LOG_HOOK_TIMEOUT", 1000)) * time.Millisecond)
+	hooks.SetMaxAbandoned(getEnvInt("LOG_HOOK_MAX_ABANDONED", logger.DefaultMaxAbandoned))
+	if a := alerterFromEnv(); a != nil {
+		AddAlerter(a)
+	}
+	store = storeFromEnv()
+	schemaMode = schemaFromEnv()
+
+	// LOG_ERROR_GROUPS max error groups kept (default 1000)
+	// groups are written every LOG_INTERVAL seconds
+	aggregator = logger.NewAggregator(Named(""), time.Duration(getEnvInt("LOG_INTERVAL", 15))*time.Second, getEnvInt("LOG_ERROR_GROUPS", 1000))
+	hooks.Add([]logger.Level{logger.ErrorLevel}, aggregator.Hook)
+}
+
+// newBackend select log backend with LOG_BACKEND env
+// "logging" use the dependency free Logging, "json", "logfmt" and "text" use
+// encoders with LOG_TIME_FORMAT (layout, unix, unix_ms or unix_ns), default is factorlog
+// "journald" send to journald, LOG_JOURNAL_SOCKET set its socket path,
+// factorlog is used if it is not available
+// "fluent" send to LOG_FLUENT_ADDR (default 127.0.0.1:24224) with the Fluent Forward
+// protocol, LOG_FLUENT_TAG (default gologs), LOG_FLUENT_MODE (message, forward or packed),
+// LOG_FLUENT_ACK=1 wait an ack of each message
+// "otlp" export to LOG_OTLP_ENDPOINT (default http://localhost:4318/v1/logs),
+// LOG_OTLP_PROTOCOL (protobuf or json), LOG_OTLP_HEADERS (key=value,...),
+// LOG_OTLP_SERVICE is the service.name (default program name)
+// LOG_UTC=1 write time in UTC
+// LOG_MULTILINE=1 keep newlines of text messages and indent continuation lines,
+// by default newlines and other control characters are escaped
+// LOG_STDERR_LEVEL write entries of this level and above (e.g. warn) to stderr,
+// the others to stdout, with factorlog, json, logfmt and text
+// LOG_ROUTE_KEY route entries into files by a field value, see newRouter
+func newBackend() logger.Log {
+	utc := os.Getenv("LOG_UTC") == "1"
+	multiline := os.Getenv("LOG_MULTILINE") == "1"
+	timeFormat := logger.TimeFormat{Layout: os.Getenv("LOG_TIME_FORMAT"), UTC: utc}
+
+	var opts []logger.Option
+	if s := os.Getenv("LOG_STDERR_LEVEL"); s != "" {
+		level, err := logger.ParseLevel(s)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "stderr level:", err)
+		} else {
+			opts = append(opts, logger.WithLevelOutput(os.Stderr, level))
+		}
+	}
+
+	var backend logger.Log
+	switch os.Getenv("LOG_BACKEND") {
+	case "logging":
+		flag := LstdFlags | Lmicroseconds
+		if utc {
+			flag |= LUTC
+		}
+		if multiline {
+			flag |= Lmultiline
+		}
+		backend = NewLogging(os.Stdout, "", flag)
+	case "json":
+		backend = logger.NewWriterSink(os.Stdout, &logger.JSONEncoder{Time: timeFormat}, opts...)
+	case "logfmt":
+		backend = logger.NewWriterSink(os.Stdout, &logger.LogfmtEncoder{Time: timeFormat}, opts...)
+	case "text":
+		backend = logger.NewWriterSink(os.Stdout, &logger.TextEncoder{Time: timeFormat, Multiline: multiline}, opts...)
+	case "journald":
+		j, err := logger.NewJournalSink(os.Getenv("LOG_JOURNAL_SOCKET"), filepath.Base(os.Args[0]))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "journald:", err)
+			break
+		}
+		backend = j
+	case "fluent":
+		backend = newFluentSink()
+	case "otlp":
+		backend = newOTLPExporter()
+	}
+	if backend == nil {
+		if utc {
+			opts = append(opts, logger.WithUTC())
+		}
+		if multiline {
+			opts = append(opts, logger.WithMultiline())
+		}
+		backend = logger.NewFactorLog(opts...)
+	}
+
+	if key := os.Getenv("LOG_ROUTE_KEY"); key != "" {
+		return newRouter(key, backend, timeFormat, multiline)
+	}
+	return backend
+}
+
+func newFluentSink() *logger.FluentSink {
+	cfg := logger.FluentConfig{
+		Addr: os.Getenv("LOG_FLUENT_ADDR"),
+		Tag:  os.Getenv("LOG_FLUENT_TAG"),
+		Ack:  os.Getenv("LOG_FLUENT_ACK") == "1",
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:24224"
+	}
+	if cfg.Tag == "" {
+		cfg.Tag = "gologs"
+	}
+	if mode := os.Getenv("LOG_FLUENT_MODE"); mode != "" {
+		m, err := logger.ParseForwardMode(mode)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "fluent:", err)
+		}
+		cfg.Mode = m
+	}
+	return logger.NewFluentSink(cfg)
 }
 
-// Error export error log
+func newOTLPExporter() *logger.OTLPExporter {
+	cfg := logger.OTLPConfig{
+		URL:     os.Getenv("LOG_OTLP_ENDPOINT"),
+		Headers: make(map[string]string),
+	}
+	if cfg.URL == "" {
+		cfg.URL = "http://localhost:4318/v1/logs"
+	}
+	if os.Getenv("LOG_OTLP_PROTOCOL") == "json" {
+		cfg.Encoding = logger.OTLPJSON
+	}
+	for _, header := range splitEnv("LOG_OTLP_HEADERS") {
+		if i := strings.IndexByte(header, '='); i > 0 {
+			cfg.Headers[strings.TrimSpace(header[:i])] = strings.TrimSpace(header[i+1:])
+		}
+	}
+	service := os.Getenv("LOG_OTLP_SERVICE")
+	if service == "" {
+		service = filepath.Base(os.Args[0])
+	}
+	cfg.Resource = []logger.Field{logger.String("service.name", service)}
+	return logger.NewOTLPExporter(cfg)
+}
+
+// newRouter write entries with the key field into LOG_ROUTE_DIR/<value>.log
+// (default logs), json or logfmt encoded with LOG_BACKEND=json or logfmt else text, rotated daily.
+// Other entries are written to fallback.
+// LOG_ROUTE_IDLE seconds before closing an unused file (default 300)
+// LOG_ROUTE_MAX_OPEN max open files (default 100)
+func newRouter(key string, fallback logger.Log, timeFormat logger.TimeFormat, multiline bool) *logger.Router {
+	dir := os.Getenv("LOG_ROUTE_DIR")
+	if dir == "" {
+		dir = "logs"
+	}
+	var enc logger.Encoder = &logger.TextEncoder{Time: timeFormat, Multiline: multiline}
+	switch os.Getenv("LOG_BACKEND") {
+	case "json":
+		enc = &logger.JSONEncoder{Time: timeFormat}
+	case "logfmt":
+		enc = &logger.LogfmtEncoder{Time: timeFormat}
+	}
+	return logger.NewRouter(logger.RouterConfig{
+		Key:      key,
+		Open:     logger.FileRoutes(dir, enc, logger.FileConfig{Interval: 24 * time.Hour}),
+		Fallback: fallback,
+		Idle:     time.Duration(getEnvInt("LOG_ROUTE_IDLE", 300)) * time.Second,
+		MaxOpen:  getEnvInt("LOG_ROUTE_MAX_OPEN", 100),
+	})
+}
+
+// SetLogger replace current log backend
+func SetLogger(l logger.Log) {
+	setBackend(l)
+	resetPipelines()
+}
+
+// setBackend set backend and Log, a redactor in front of it if there are rules
+func setBackend(l logger.Log) {
+	backend = logger.NewSequencer(l)
+	Log = backend
+	if !redaction.Empty() {
+		Log = logger.NewRedactor(backend, redaction)
+	}
+}
+
+// Error export error log, with the caller location for error grouping
 func Error(v ...interface{}) {
 	if OffLog != "1" {
-		go Log.ERROR(v...)
+		l := getPipeline("").head
+		async(l.ERROR, withCaller(v, 1))
 	}
 }
 
 // Info export none error log
 func Info(v ...interface{}) {
 	if OffLog != "1" {
-		go Log.INFO(v...)
+		l := getPipeline("").head
+		async(l.INFO, v)
 	}
 }
 
 // Debug export none error log
+// Debug entries are always recorded, they are written only with DEBUG=1.
+// Each call therefore formats and records its entry even when debug output
+// is off, it is not free like it was before the flight recorder.
 func Debug(v ...interface{}) {
-	if os.Getenv("DEBUG") == "1" && OffLog != "1" {
-		go Log.DEBUG(v...)
+	if OffLog != "1" {
+		l := getPipeline("").head
+		async(l.DEBUG, v)
 	}
 }
 
 // Warn export none error log
 func Warn(v ...interface{}) {
 	if OffLog != "1" {
-		go Log.WARN(v...)
+		l := getPipeline("").head
+		async(l.WARN, v)
 	}
 }
 
@@ -50,4 +245,5 @@ func Stack(v ...string) {
 	// 	Log.STACK(v...)
 	// }
 	go Log.STACK(v...)
+	go stackAlerters(redaction.RedactStrings(v)...)
 }
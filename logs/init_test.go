@@ -0,0 +1,87 @@
+package logs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lamhai1401/gologs/logger"
+)
+
+func TestRedactLog(t *testing.T) {
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+	SetRedaction(logger.NewRedaction().Pattern(logger.DefaultPatterns(logger.RedactFull)...))
+	defer SetRedaction(logger.NewRedaction())
+
+	Log.INFO("direct write from 10.1.2.3")
+	Info("facade write from 10.1.2.3")
+	waitPending()
+	if got := out.String(); strings.Contains(got, "10.1.2.3") || strings.Count(got, "[REDACTED]") != 2 {
+		t.Errorf("not redacted: %q", got)
+	}
+}
+
+func TestErrorCaller(t *testing.T) {
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+
+	callers := make(chan string, 2)
+	remove := AddHook([]logger.Level{logger.ErrorLevel}, func(e *logger.Entry) error {
+		value, _ := e.Field(logger.CallerKey)
+		callers <- fmt.Sprint(value)
+		return nil
+	})
+	defer remove()
+
+	Error("direct")
+	wrapper := func(v ...interface{}) {
+		Named("").ERROR(append(v, logger.Caller(1))...)
+	}
+	wrapper("wrapped")
+	waitPending()
+
+	got := []string{<-callers, <-callers}
+	sort.Strings(got)
+	for _, caller := range got {
+		if !strings.HasPrefix(caller, "logs/init_test.go:") {
+			t.Errorf("caller %q", caller)
+		}
+	}
+	if got[0] == got[1] {
+		t.Errorf("wrapper caller not kept: %q", got)
+	}
+	if strings.Contains(out.String(), "caller=") {
+		t.Errorf("caller written without LOG_CALLER: %q", out.String())
+	}
+
+	// error groups keep their location in the summary
+	aggregator.Summary()
+	waitPending()
+	if !strings.Contains(out.String(), "group_caller=logs/init_test.go:") {
+		t.Errorf("summary without caller: %q", out.String())
+	}
+}
+
+func TestDirectLogStamped(t *testing.T) {
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+
+	Log.INFO("direct")
+	if !strings.Contains(out.String(), "seq=") || !strings.Contains(out.String(), "session_id="+logger.Session()) {
+		t.Errorf("direct write should be stamped, got %q", out.String())
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	for addr, want := range map[string]string{
+		":9099":        "127.0.0.1:9099",
+		"0.0.0.0:9099": "0.0.0.0:9099",
+		"[::1]:9099":   "[::1]:9099",
+	} {
+		if got := listenAddr(addr); got != want {
+			t.Errorf("listenAddr(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -1,39 +1,45 @@
 package logs
 
 import (
-	"fmt"
 	"log"
-	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/lamhai1401/gologs/logger"
 )
 
 // Logger to export log into a file
 type Logger struct {
-	info *log.Logger
-	err  *log.Logger
+	info  *log.Logger
+	err   *log.Logger
+	file  *logger.RotatingFile
+	clock logger.Clock
 }
 
 func (l *Logger) init() error {
-	var generalLog *os.File
-
 	absPath, err := filepath.Abs("./logs")
 	if err != nil {
 		return err
 	}
-	generalLog, err = os.OpenFile(absPath+fmt.Sprintf("/%s.log", time.Now().Local().String()), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+
+	// the file is named by the start time and rotated every day
+	l.file, err = logger.NewRotatingFile(logger.FileConfig{
+		Path:     filepath.Join(absPath, l.clock.Now().Format("2006-01-02T15-04-05")+".log"),
+		Interval: 24 * time.Hour,
+		Clock:    l.clock,
+	})
 	if err != nil {
 		return err
 	}
 
 	l.info = log.New(
-		generalLog,
+		l.file,
 		"[INFO]",
 		log.Ldate|log.Ltime|log.Lshortfile,
 	)
 
 	l.err = log.New(
-		generalLog,
+		l.file,
 		"[ERROR]",
 		log.Ldate|log.Ltime|log.Lshortfile,
 	)
@@ -51,7 +57,7 @@ func (l *Logger) ERROR(str string) {
 }
 
 func newLogger() *Logger {
-	l := &Logger{}
+	l := &Logger{clock: logger.SystemClock}
 	l.init()
 	return l
 }
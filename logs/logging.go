@@ -5,8 +5,13 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/lamhai1401/gologs/logger"
 )
 
 const (
@@ -24,26 +29,108 @@ const (
 	LUTC // if Ldate or Ltime is set, use UTC rather than the local time zone
 	// Lmsgprefix linter
 	Lmsgprefix // move the "prefix" from the beginning of the line to before the message
+	// Lmultiline linter
+	Lmultiline // keep newlines of the message and indent continuation lines instead of escaping them
 	// LstdFlags linter
 	LstdFlags = Ldate | Ltime // initial values for the standard logger
 )
 
-// Logging for cmd log
+// Logging is a dependency free logger.Log backend base on the standard log pkg
 type Logging struct {
-	prefix string    // prefix on each line to identify the logger (but see Lmsgprefix)
-	buf    []byte    // for accumulating text to write
-	out    io.Writer // destination for output
-	flag   int       // properties
+	prefix string             // prefix on each line to identify the logger (but see Lmsgprefix)
+	out    io.Writer          // destination for output
+	flag   int                // properties
+	stacks *logger.AdvanceMap // save for stack counters
+	clock  logger.Clock       // time of entries and stack ticker
+	serve  sync.Once          // start stack ticker on first STACK
+	stop   chan struct{}      // closed by Close to stop the stack ticker
+	closed sync.Once
 	mutex  sync.Mutex
 }
 
-// New creates a new Logger. The out variable sets the
+// bufPool keep buffers for accumulating text to write
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// NewLogging creates a new Logging. The out variable sets the
 // destination to which log data will be written.
 // The prefix appears at the beginning of each generated log line, or
 // after the log header if the Lmsgprefix flag is provided.
 // The flag argument defines the logging properties.
+func NewLogging(out io.Writer, prefix string, flag int) *Logging {
+	l := &Logging{
+		out:    out,
+		prefix: prefix,
+		flag:   flag,
+		stacks: logger.NewAdvanceMap(),
+		clock:  logger.SystemClock,
+		stop:   make(chan struct{}),
+	}
+	return l
+}
+
 func newLogging() *Logging {
-	return &Logging{out: os.Stderr, prefix: "", flag: LstdFlags}
+	return NewLogging(os.Stderr, "", LstdFlags)
+}
+
+// SetOutput sets the output destination for the logger.
+func (l *Logging) SetOutput(w io.Writer) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.out = w
+}
+
+// Writer returns the output destination for the logger.
+func (l *Logging) Writer() io.Writer {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.out
+}
+
+// SetClock sets the clock of entry time and stack ticker, call it before the first STACK.
+func (l *Logging) SetClock(clock logger.Clock) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.clock = clock
+}
+
+// Clock return the clock of entry time and stack ticker
+func (l *Logging) Clock() logger.Clock {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.clock
+}
+
+// Flags returns the output flags for the logger.
+func (l *Logging) Flags() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.flag
+}
+
+// SetFlags sets the output flags for the logger.
+func (l *Logging) SetFlags(flag int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.flag = flag
+}
+
+// Prefix returns the output prefix for the logger.
+func (l *Logging) Prefix() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.prefix
+}
+
+// SetPrefix sets the output prefix for the logger.
+func (l *Logging) SetPrefix(prefix string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.prefix = prefix
 }
 
 // Output writes the output for a logging event. The string s contains
@@ -53,29 +140,40 @@ func newLogging() *Logging {
 // provided for generality, although at the moment on all pre-defined
 // paths it will be 2.
 func (l *Logging) Output(calldepth int, s string) error {
-	now := time.Now() // get this early.
+	return l.write(l.Clock().Now(), calldepth+1, s)
+}
+
+// WriteEntry write a prepared entry with its own time
+func (l *Logging) WriteEntry(e *logger.Entry) error {
+	return l.write(e.Time, 2, fmt.Sprintf("[%s] %s", e.Level, e.Text()))
+}
+
+// write output s with the header of time now
+func (l *Logging) write(now time.Time, calldepth int, s string) error {
 	var file string
 	var line int
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-	if l.flag&(Lshortfile) != 0 {
-		// Release lock while getting caller info - it's expensive.
-		l.mutex.Unlock()
+	if l.Flags()&(Lshortfile|Llongfile) != 0 {
+		// get caller info without lock - it's expensive.
 		var ok bool
 		_, file, line, ok = runtime.Caller(calldepth)
 		if !ok {
 			file = "???"
 			line = 0
 		}
-		l.mutex.Lock()
 	}
-	l.buf = l.buf[:0]
-	l.formatHeader(&l.buf, now, file, line)
-	l.buf = append(l.buf, s...)
-	if len(s) == 0 || s[len(s)-1] != '\n' {
-		l.buf = append(l.buf, '\n')
-	}
-	_, err := l.out.Write(l.buf)
+
+	bp := bufPool.Get().(*[]byte)
+	defer bufPool.Put(bp)
+	buf := (*bp)[:0]
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.formatHeader(&buf, now, file, line)
+	// escape control characters so a message is one line, see logger.AppendEscaped
+	buf = logger.AppendEscaped(buf, strings.TrimSuffix(s, "\n"), l.flag&Lmultiline != 0)
+	buf = append(buf, '\n')
+	*bp = buf
+	_, err := l.out.Write(buf)
 	return err
 }
 
@@ -153,12 +251,120 @@ func itoa(buf *[]byte, i int, wid int) {
 	*buf = append(*buf, b[bp:]...)
 }
 
-// INFO linter
-func (l *Logging) INFO(v ...interface{}) {
-	l.Output(1, fmt.Sprintln(fmt.Sprintf("[INFO] %s", v...)))
+// output write a message with its severity tag
+func (l *Logging) output(severity string, v ...interface{}) {
+	l.Output(3, fmt.Sprintf("[%s] %s", severity, fmt.Sprintln(v...)))
 }
 
 // ERROR linter
 func (l *Logging) ERROR(v ...interface{}) {
-	l.Output(2, fmt.Sprintln(fmt.Sprintf("[ERROR] %s", v...)))
+	l.output("ERROR", v...)
+}
+
+// INFO linter
+func (l *Logging) INFO(v ...interface{}) {
+	l.output("INFO", v...)
+}
+
+// WARN linter
+func (l *Logging) WARN(v ...interface{}) {
+	l.output("WARN", v...)
+}
+
+// DEBUG linter
+func (l *Logging) DEBUG(v ...interface{}) {
+	l.output("DEBUG", v...)
+}
+
+// PANIC linter
+func (l *Logging) PANIC(v ...interface{}) {
+	l.output("PANIC", v...)
+}
+
+// FATAL linter
+func (l *Logging) FATAL(v ...interface{}) {
+	l.output("FATAL", v...)
+}
+
+// Sync commit the output if it is a file
+func (l *Logging) Sync() error {
+	return logger.SyncWriter(l.Writer())
+}
+
+// STACK increase counter of each id, counters are printed every LOG_INTERVAL seconds
+func (l *Logging) STACK(values ...string) {
+	l.serve.Do(func() {
+		Supervise("logging stacks", l.serveStacks)
+	})
+	for _, id := range values {
+		l.setStack(id, l.getStack(id)+1)
+	}
+}
+
+func (l *Logging) getStack(id string) int {
+	if t, in := l.stacks.Get(id); in {
+		if stack, ok := t.(int); ok {
+			return stack
+		}
+	}
+	return 0
+}
+
+func (l *Logging) setStack(id string, count int) {
+	l.stacks.Set(id, count)
+}
+
+// dumpStacks print and reset current counters, stamped like entries
+func (l *Logging) dumpStacks() {
+	tmp := l.stacks.Capture()
+	if len(tmp) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(tmp))
+	for k := range tmp {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, tmp[k])
+	}
+	fmt.Fprintf(&b, " %s=%d %s=%s", logger.SeqKey, logger.NextSeq(), logger.SessionKey, logger.Session())
+	l.Output(2, "[STACK]"+b.String())
+}
+
+// serveStacks print stacking until Close
+func (l *Logging) serveStacks() {
+	ticker := l.Clock().NewTicker(time.Duration(getInterval()) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			l.dumpStacks()
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Close stop the stack ticker and print the pending counters
+func (l *Logging) Close() error {
+	l.closed.Do(func() {
+		close(l.stop)
+		l.dumpStacks()
+	})
+	return nil
+}
+
+func getInterval() int {
+	i := 15
+	if interval := os.Getenv("LOG_INTERVAL"); interval != "" {
+		j, err := strconv.Atoi(interval)
+		if err == nil {
+			i = j
+		}
+	}
+	return i
 }
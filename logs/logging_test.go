@@ -0,0 +1,166 @@
+package logs
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lamhai1401/gologs/logger"
+)
+
+var _ logger.Log = (*Logging)(nil)
+
+func TestLoggingLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogging(buf, "app ", 0)
+	l.ERROR("error", 1)
+	l.WARN("warn")
+	l.INFO("info")
+	l.DEBUG("debug")
+
+	want := "app [ERROR] error 1\napp [WARN] warn\napp [INFO] info\napp [DEBUG] debug\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLoggingEscape(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogging(buf, "", 0)
+	l.INFO("Remove id", "a\n[ERROR] forged\x1b[0m")
+	if want := `[INFO] Remove id a\n[ERROR] forged\x1b[0m` + "\n"; buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	l.SetFlags(Lmultiline)
+	l.ERROR("panic: boom\ngoroutine 1")
+	if want := "[ERROR] panic: boom\n    goroutine 1\n"; buf.String() != want {
+		t.Fatalf("multiline got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestLoggingFlags(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogging(buf, "", Lshortfile)
+	l.INFO("caller")
+	if !strings.HasPrefix(buf.String(), "logging_test.go:") {
+		t.Fatalf("expect short file, got %q", buf.String())
+	}
+
+	buf.Reset()
+	l.SetFlags(Llongfile | Lmsgprefix)
+	l.SetPrefix("p: ")
+	l.INFO("caller")
+	if !strings.Contains(buf.String(), "/logs/logging_test.go:") || !strings.Contains(buf.String(), ": p: [INFO]") {
+		t.Fatalf("expect long file and msg prefix, got %q", buf.String())
+	}
+
+	out := &bytes.Buffer{}
+	l.SetOutput(out)
+	l.SetFlags(LstdFlags | LUTC)
+	l.INFO("date")
+	if l.Writer() != out || len(out.String()) < len("2006/01/02 15:04:05 ") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestLoggingStack(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewLogging(buf, "", 0)
+	l.STACK("b", "a", "a")
+	l.dumpStacks()
+	if !strings.HasPrefix(buf.String(), "[STACK] a=2 b=1 seq=") || !strings.HasSuffix(buf.String(), " session_id="+logger.Session()+"\n") {
+		t.Fatalf("unexpected stacks %q", buf.String())
+	}
+
+	buf.Reset()
+	l.dumpStacks()
+	if buf.Len() != 0 {
+		t.Fatalf("stacks should be reset, got %q", buf.String())
+	}
+}
+
+// syncBuffer is a buffer safe to read while a goroutine writes
+type syncBuffer struct {
+	buf   bytes.Buffer
+	mutex sync.Mutex
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.buf.String()
+}
+
+func TestLoggingClock(t *testing.T) {
+	out := &syncBuffer{}
+	clock := logger.NewManualClock(time.Date(2020, 9, 10, 1, 2, 3, 0, time.UTC))
+	l := NewLogging(out, "", LstdFlags|LUTC)
+	l.SetClock(clock)
+
+	l.INFO("stamped")
+	l.STACK("a")
+	if out.String() != "2020/09/10 01:02:03 [INFO] stamped\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	// wait for the stack ticker to start then move to the next interval
+	for i := 0; i < 100 && !strings.Contains(out.String(), "[STACK]"); i++ {
+		time.Sleep(10 * time.Millisecond)
+		clock.Add(time.Duration(getInterval()) * time.Second)
+	}
+	if !strings.Contains(out.String(), "[STACK] a=1 seq=") {
+		t.Fatalf("expect stack counters, got %q", out.String())
+	}
+}
+
+func TestLoggingClose(t *testing.T) {
+	out := &syncBuffer{}
+	clock := logger.NewManualClock(time.Unix(0, 0))
+	l := NewLogging(out, "", 0)
+	l.SetClock(clock)
+
+	l.STACK("a")
+	l.Close()
+	if !strings.Contains(out.String(), "[STACK] a=1 seq=") {
+		t.Fatalf("expect pending counters, got %q", out.String())
+	}
+
+	// the ticker is stopped
+	l.STACK("b")
+	for i := 0; i < 10; i++ {
+		time.Sleep(time.Millisecond)
+		clock.Add(time.Duration(getInterval()) * time.Second)
+	}
+	if strings.Contains(out.String(), "b=1") {
+		t.Errorf("counters written after Close: %q", out.String())
+	}
+}
+
+func TestLoggingDecode(t *testing.T) {
+	out := &bytes.Buffer{}
+	now := time.Date(2020, 9, 10, 1, 2, 3, 456789000, time.Local)
+	l := NewLogging(out, "", LstdFlags|Lmicroseconds)
+	l.SetClock(logger.NewManualClock(now))
+	l.WARN("stream closed", logger.String("stream_id", "abc"))
+
+	e, err := logger.AutoDecoder{}.Decode(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Time.Equal(now) || e.Level != logger.WarnLevel || e.Message != "stream closed" {
+		t.Errorf("got %v %v %q from %q", e.Time, e.Level, e.Message, out.String())
+	}
+	if v, _ := e.Field("stream_id"); v != "abc" {
+		t.Errorf("stream_id %v from %q", v, out.String())
+	}
+}
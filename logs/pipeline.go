@@ -0,0 +1,465 @@
+package logs
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lamhai1401/gologs/logger"
+)
+
+// pipelines save the chain of logs behind each named log
+var pipelines = logger.NewAdvanceMap()
+var pipelineMutex sync.Mutex
+
+// pipelineGen change each time every pipeline is dropped, facades caching
+// a pipeline get it again when it changed
+var pipelineGen int64
+
+// pipeline is the chain of logs behind a named log
+//
+//	(tail sampler) -> schema -> redactor -> hooks -> flight recorder -> sampler -> sequencer -> backend
+type pipeline struct {
+	head     logger.Log          // first log of the chain
+	recorder *logger.Recorder    // flight recorder
+	sampler  *logger.Sampler     // nil if sampling is off
+	tail     *logger.TailSampler // in front of head for logs with context
+	backend  logger.Log          // end of the chain, its clock stamps typed entries
+}
+
+// redaction rules of every pipeline, nil if none
+var redaction *logger.Redaction
+
+// hooks of every pipeline
+var hooks *logger.Hooks
+
+// aggregator group errors of every log
+var aggregator *logger.Aggregator
+
+// store keep recent entries of every log, nil if off
+var store *logger.Store
+
+// events is the catalogue of events checked by every pipeline
+var events = logger.NewEventRegistry()
+
+// schemaMode is what pipelines do with entries not matching their event schema
+var schemaMode logger.SchemaMode
+
+// alerters fed with STACK ids
+var alerters []*logger.Alerter
+var alertMutex sync.Mutex
+
+// newPipeline build the chain of name in front of Log
+// Sampling is off by default, with LOG_SAMPLE_FIRST > 0 the first entries of
+// the same message are written each LOG_SAMPLE_INTERVAL seconds (default 1),
+// then every LOG_SAMPLE_THEREAFTER-th (default 100)
+func newPipeline(name string) *pipeline {
+	p := &pipeline{backend: backend}
+	var next logger.Log = backend
+	if first := getEnvInt("LOG_SAMPLE_FIRST", 0); first > 0 {
+		interval := getEnvInt("LOG_SAMPLE_INTERVAL", 1)
+		if interval <= 0 {
+			fmt.Fprintln(os.Stderr, "sampler: LOG_SAMPLE_INTERVAL must be positive, using 1")
+			interval = 1
+		}
+		p.sampler = logger.NewSampler(backend, time.Duration(interval)*time.Second, first, getEnvInt("LOG_SAMPLE_THEREAFTER", 100))
+		next = p.sampler
+	}
+
+	// the recorder is in front of the sampler so it keeps the dropped entries
+	p.recorder = logger.NewRecorder(name, next, getEnvInt("LOG_RECORDER_SIZE", 100), getLevel())
+	p.head = p.recorder
+
+	// the caller of errors is used by hooks to group them,
+	// it is written only with LOG_CALLER=1
+	if os.Getenv("LOG_CALLER") != "1" {
+		p.head = logger.NewDropFields(p.head, logger.CallerKey)
+	}
+
+	// hooks see every entry, even the sampled ones
+	p.head = logger.NewHookLog(p.head, hooks)
+
+	// redact first so recorded and sampled entries are already masked
+	if !redaction.Empty() {
+		p.head = logger.NewRedactor(p.head, redaction)
+	}
+
+	// check events before redaction changes field values
+	if schemaMode != logger.SchemaOff {
+		p.head = logger.NewSchemaLog(p.head, events, schemaMode)
+	}
+
+	// LOG_TAIL_SIZE debug entries are kept for each of LOG_TAIL_IDS correlation ids
+	// during LOG_TAIL_TTL seconds
+	p.tail = logger.NewTailSampler(
+		p.head,
+		getEnvInt("LOG_TAIL_SIZE", 100),
+		getEnvInt("LOG_TAIL_IDS", 1000),
+		time.Duration(getEnvInt("LOG_TAIL_TTL", 60))*time.Second,
+	)
+	return p
+}
+
+// now return the time of the backend clock
+func (p *pipeline) now() time.Time {
+	return logger.ClockOf(p.backend).Now()
+}
+
+// close stop background work of the chain
+func (p *pipeline) close() {
+	p.tail.Stop()
+	if p.sampler != nil {
+		p.sampler.Stop()
+	}
+}
+
+// getPipeline return pipeline of name, create it if not exist
+func getPipeline(name string) *pipeline {
+	pipelineMutex.Lock()
+	defer pipelineMutex.Unlock()
+	if t, ok := pipelines.Get(name); ok {
+		if p, ok := t.(*pipeline); ok {
+			return p
+		}
+	}
+	p := newPipeline(name)
+	pipelines.Set(name, p)
+	return p
+}
+
+// AddHook register fn for entries of levels, no level means every level.
+// Hooks run in the log goroutine, each one for at most LOG_HOOK_TIMEOUT
+// milliseconds (default 1000). The returned func remove the hook.
+func AddHook(levels []logger.Level, fn logger.HookFunc) func() {
+	return hooks.Add(levels, fn)
+}
+
+// AddAlerter feed a with entries of every log and STACK ids.
+// The returned func stop feeding it.
+func AddAlerter(a *logger.Alerter) func() {
+	alertMutex.Lock()
+	defer alertMutex.Unlock()
+	alerters = append(alerters, a)
+	remove := hooks.Add(nil, a.Hook)
+	return func() {
+		remove()
+		alertMutex.Lock()
+		defer alertMutex.Unlock()
+		for i, item := range alerters {
+			if item == a {
+				alerters = append(alerters[:i:i], alerters[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// stackAlerters feed alerters with STACK ids
+func stackAlerters(ids ...string) {
+	alertMutex.Lock()
+	defer alertMutex.Unlock()
+	for _, a := range alerters {
+		a.STACK(ids...)
+	}
+}
+
+// alerterFromEnv return an alerter counting ERROR entries, nil if LOG_ALERT_WEBHOOK is not set
+// LOG_ALERT_FORMAT: generic (default) or slack
+// LOG_ALERT_THRESHOLD: fire on more than this many errors (default 50)
+// LOG_ALERT_WINDOW: in this many seconds (default 60)
+// LOG_ALERT_GROUP_BY: count per value of this field (e.g. stream_id, set by forwarder logs)
+// LOG_ALERT_COOLDOWN: seconds before a group fire again (default 300)
+func alerterFromEnv() *logger.Alerter {
+	url := os.Getenv("LOG_ALERT_WEBHOOK")
+	if url == "" {
+		return nil
+	}
+	format := logger.AlertGeneric
+	if os.Getenv("LOG_ALERT_FORMAT") == "slack" {
+		format = logger.AlertSlack
+	}
+	return logger.NewAlerter(url, format, 10*time.Second, logger.AlertRule{
+		Name:      "error rate",
+		Level:     logger.ErrorLevel,
+		GroupBy:   os.Getenv("LOG_ALERT_GROUP_BY"),
+		Threshold: getEnvInt("LOG_ALERT_THRESHOLD", 50),
+		Window:    time.Duration(getEnvInt("LOG_ALERT_WINDOW", 60)) * time.Second,
+		Cooldown:  time.Duration(getEnvInt("LOG_ALERT_COOLDOWN", 300)) * time.Second,
+	})
+}
+
+// storeFromEnv return a store of the last LOG_STORE_SIZE megabytes of entries
+// fed by every log, nil if LOG_STORE_SIZE is not set.
+// LOG_STORE_ADDR serve its HTTP API (e.g. ":9099"), see logger.Store.Handler.
+// The API is not authenticated, a port alone listens on loopback only, see listenAddr
+func storeFromEnv() *logger.Store {
+	size := getEnvInt("LOG_STORE_SIZE", 0)
+	if size <= 0 {
+		return nil
+	}
+	s := logger.NewStore(size << 20)
+	hooks.Add(nil, s.Hook)
+	if addr := os.Getenv("LOG_STORE_ADDR"); addr != "" {
+		Go("log store http", func() {
+			if err := http.ListenAndServe(listenAddr(addr), s.Handler()); err != nil {
+				Error("log store:", err)
+			}
+		})
+	}
+	return s
+}
+
+// listenAddr return addr, on loopback if it has no host (e.g. ":9099").
+// The log APIs are not authenticated, listen on every interface with
+// an explicit host like "0.0.0.0:9099" behind a firewall or a proxy.
+func listenAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "127.0.0.1" + addr
+	}
+	return addr
+}
+
+// Store return the store of recent entries, nil if LOG_STORE_SIZE is not set
+// Mount its Handler to query it from another server:
+//
+//	http.Handle("/logs/", http.StripPrefix("/logs", logs.Store().Handler()))
+func Store() *logger.Store {
+	return store
+}
+
+// schemaFromEnv read the schema mode of LOG_SCHEMA_MODE: annotate (default) add a
+// schema_error field to entries not matching their event schema, strict
+// replace them with an ERROR entry, off.
+// LOG_EVENTS_ADDR serve the catalogue of events (e.g. ":9098"), see logger.EventRegistry.Handler,
+// it is not authenticated and a port alone listens on loopback only
+func schemaFromEnv() logger.SchemaMode {
+	mode := logger.SchemaAnnotate
+	if s := os.Getenv("LOG_SCHEMA_MODE"); s != "" {
+		m, err := logger.ParseSchemaMode(s)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "schema:", err)
+		} else {
+			mode = m
+		}
+	}
+	if addr := os.Getenv("LOG_EVENTS_ADDR"); addr != "" {
+		Go("log events http", func() {
+			if err := http.ListenAndServe(listenAddr(addr), events.Handler()); err != nil {
+				Error("log events:", err)
+			}
+		})
+	}
+	return mode
+}
+
+// RegisterEvent declare an event and return its field, it panics if the name
+// is registered with another schema
+//
+//	var streamClosed = logs.RegisterEvent(logger.EventSchema{
+//		Name:   "stream.closed",
+//		Fields: []logger.FieldSpec{logger.Required("stream_id", logger.StringType, "")},
+//	})
+//	logs.Info("stream closed", streamClosed, logger.String("stream_id", id))
+func RegisterEvent(schema logger.EventSchema) logger.Field {
+	return events.MustRegister(schema)
+}
+
+// Events return the catalogue of registered events
+// Mount its Handler to list them from another server:
+//
+//	http.Handle("/events", logs.Events().Handler())
+func Events() *logger.EventRegistry {
+	return events
+}
+
+// ErrorGroups return errors grouped by fingerprint, most frequent first
+func ErrorGroups() []logger.ErrorGroup {
+	return aggregator.Groups()
+}
+
+// ErrorGroup return the error group of a fingerprint
+func ErrorGroup(fingerprint string) (logger.ErrorGroup, bool) {
+	return aggregator.Group(fingerprint)
+}
+
+// SetRedaction set rules masking sensitive data of every log
+func SetRedaction(r *logger.Redaction) {
+	redaction = r
+	setBackend(backend)
+	resetPipelines()
+}
+
+// redactionFromEnv build redaction rules
+// LOG_REDACT_FIELDS: comma separated field names masked whatever the value
+// LOG_REDACT_PATTERNS: comma separated built in patterns (bearer, email, ipv4)
+// LOG_REDACT_MODE: full (default), partial or hash
+func redactionFromEnv() *logger.Redaction {
+	mode, err := logger.ParseRedactMode(os.Getenv("LOG_REDACT_MODE"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "redact:", err)
+		mode = logger.RedactFull
+	}
+
+	r := logger.NewRedaction()
+	for _, name := range splitEnv("LOG_REDACT_FIELDS") {
+		r.Field(mode, name)
+	}
+	for _, name := range splitEnv("LOG_REDACT_PATTERNS") {
+		found := false
+		for _, p := range logger.DefaultPatterns(mode) {
+			if p.Name == name {
+				r.Pattern(p)
+				found = true
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "redact: unknown pattern %q\n", name)
+		}
+	}
+	return r
+}
+
+// Recorder return flight recorder of name, create it if not exist
+// The ring size is LOG_RECORDER_SIZE (default 100), DEBUG=1 enable debug output
+func Recorder(name string) *logger.Recorder {
+	return getPipeline(name).recorder
+}
+
+// withCaller return v with the caller field of the function skip frames above,
+// v is kept as is if it already has one, like when a wrapper pass logger.Caller(1)
+func withCaller(v []interface{}, skip int) []interface{} {
+	for _, value := range v {
+		if field, ok := value.(logger.Field); ok && field.Key == logger.CallerKey {
+			return v
+		}
+	}
+	return append(v[:len(v):len(v)], logger.Caller(skip+1))
+}
+
+// named is the facade of a named log
+type named struct {
+	name string
+}
+
+// Named return a log of name, every entry is kept by its flight recorder
+func Named(name string) logger.Log {
+	return &named{name: name}
+}
+
+// ERROR linter
+// Wrappers pass their own caller field to record the line calling them
+func (n *named) ERROR(v ...interface{}) {
+	if OffLog != "1" {
+		l := getPipeline(n.name).head
+		async(l.ERROR, withCaller(v, 1))
+	}
+}
+
+// INFO linter
+func (n *named) INFO(v ...interface{}) {
+	if OffLog != "1" {
+		l := getPipeline(n.name).head
+		async(l.INFO, v)
+	}
+}
+
+// WARN linter
+func (n *named) WARN(v ...interface{}) {
+	if OffLog != "1" {
+		l := getPipeline(n.name).head
+		async(l.WARN, v)
+	}
+}
+
+// DEBUG linter
+func (n *named) DEBUG(v ...interface{}) {
+	if OffLog != "1" {
+		l := getPipeline(n.name).head
+		async(l.DEBUG, v)
+	}
+}
+
+// PANIC write an entry then panic with its message
+func (n *named) PANIC(v ...interface{}) {
+	logPanic(n.name, v, withCaller(v, 1))
+}
+
+// FATAL write an entry then exit, see Fatal
+func (n *named) FATAL(v ...interface{}) {
+	fatal(n.name, withCaller(v, 1))
+}
+
+// STACK linter
+func (n *named) STACK(v ...string) {
+	l := getPipeline(n.name).head
+	go l.STACK(v...)
+}
+
+// Forget remove the named log of name
+func Forget(name string) {
+	pipelineMutex.Lock()
+	defer pipelineMutex.Unlock()
+	if t, ok := pipelines.Get(name); ok {
+		pipelines.Delete(name)
+		if p, ok := t.(*pipeline); ok {
+			p.close()
+		}
+	}
+}
+
+// Dump write recorded entries of name
+func Dump(name string) {
+	Recorder(name).Dump()
+}
+
+// Recover must be deferred directly, it recovers a panic,
+// logs it with stack trace and dumps the recorder of name
+//
+//	defer logs.Recover("fwd")
+func Recover(name string) {
+	if value := recover(); value != nil {
+		Recorder(name).LogPanic(value)
+	}
+}
+
+// resetPipelines drop all named logs, they will be created again with current backend
+func resetPipelines() {
+	atomic.AddInt64(&pipelineGen, 1)
+	for _, key := range pipelines.GetKeys() {
+		Forget(key)
+	}
+}
+
+func getLevel() logger.Level {
+	if os.Getenv("DEBUG") == "1" {
+		return logger.DebugLevel
+	}
+	return logger.InfoLevel
+}
+
+// splitEnv return trimmed none empty values of a comma separated env
+func splitEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvInt(key string, def int) int {
+	i := def
+	if value := os.Getenv(key); value != "" {
+		j, err := strconv.Atoi(value)
+		if err == nil {
+			i = j
+		}
+	}
+	return i
+}
@@ -0,0 +1,35 @@
+package logs
+
+import (
+	"time"
+
+	"github.com/lamhai1401/gologs/logger"
+)
+
+// supervisor run goroutines of Go and Supervise
+// LOG_RESTART_BACKOFF first restart delay in milliseconds (default 100)
+// LOG_RESTART_MAX_BACKOFF restart delay limit in milliseconds (default 30000)
+var supervisor = logger.NewSupervisor(
+	Named(""),
+	time.Duration(getEnvInt("LOG_RESTART_BACKOFF", 100))*time.Millisecond,
+	time.Duration(getEnvInt("LOG_RESTART_MAX_BACKOFF", 30000))*time.Millisecond,
+)
+
+// Go run fn in a goroutine of name, a panic is recovered, logged with
+// its stack trace and counted, fn is not restarted
+//
+//	logs.Go("fwd/client-1", func() { ... })
+func Go(name string, fn func()) {
+	supervisor.Go(name, fn)
+}
+
+// Supervise run fn in a goroutine of name like Go, fn is run again with
+// backoff after a panic until it returns
+func Supervise(name string, fn func()) {
+	supervisor.Supervise(name, fn)
+}
+
+// Panics return number of recovered panics of the goroutine name
+func Panics(name string) int {
+	return supervisor.Panics(name)
+}
@@ -0,0 +1,94 @@
+package logs
+
+import (
+	"sync/atomic"
+
+	"github.com/lamhai1401/gologs/logger"
+)
+
+// Typed is the facade of a named log for hot paths like RTP forwarding.
+// Its methods take a message and typed fields:
+//
+//	logs.NewTyped(id).Info("packet forwarded", logger.String("stream_id", id), logger.Int("size", n))
+//
+// The entry is built without boxing and goes through the pipeline of the name
+// in a log goroutine, so hooks and dumps never block the caller. Like the level
+// methods it is recorded, hooked, sampled and dumped on error. The fields must
+// not be changed after the call. DEBUG entries are dropped without allocation
+// unless DEBUG=1, they are not recorded.
+type Typed struct {
+	name  string
+	level logger.Level
+	cache atomic.Value // typedPipeline
+}
+
+// typedPipeline is the pipeline of a Typed and the generation it was got in
+type typedPipeline struct {
+	p   *pipeline
+	gen int64
+}
+
+// NewTyped return the typed facade of the log of name
+func NewTyped(name string) *Typed {
+	return &Typed{
+		name:  name,
+		level: getLevel(),
+	}
+}
+
+// Debug linter
+func (t *Typed) Debug(msg string, fields ...logger.Field) {
+	if t.level <= logger.DebugLevel {
+		t.log(logger.DebugLevel, msg, fields)
+	}
+}
+
+// Info linter
+func (t *Typed) Info(msg string, fields ...logger.Field) {
+	t.log(logger.InfoLevel, msg, fields)
+}
+
+// Warn linter
+func (t *Typed) Warn(msg string, fields ...logger.Field) {
+	t.log(logger.WarnLevel, msg, fields)
+}
+
+// Error log msg with the caller location, wrappers pass their own logger.Caller(1)
+func (t *Typed) Error(msg string, fields ...logger.Field) {
+	if OffLog == "1" {
+		return
+	}
+	for _, field := range fields {
+		if field.Key == logger.CallerKey {
+			t.log(logger.ErrorLevel, msg, fields)
+			return
+		}
+	}
+	t.log(logger.ErrorLevel, msg, append(fields[:len(fields):len(fields)], logger.Caller(1)))
+}
+
+func (t *Typed) log(level logger.Level, msg string, fields []logger.Field) {
+	if OffLog == "1" {
+		return
+	}
+	p := t.pipeline()
+	e := &logger.Entry{
+		Time:    p.now(),
+		Level:   level,
+		Message: msg,
+		Fields:  fields,
+	}
+	asyncEntry(p.head, e)
+}
+
+// pipeline return the cached pipeline of the name, got again after SetLogger
+// or SetRedaction. A forgotten name keeps its pipeline instead of building a new one.
+func (t *Typed) pipeline() *pipeline {
+	gen := atomic.LoadInt64(&pipelineGen)
+	if c, ok := t.cache.Load().(typedPipeline); ok && c.gen == gen {
+		return c.p
+	}
+	p := getPipeline(t.name)
+	t.cache.Store(typedPipeline{p: p, gen: gen})
+	return p
+}
@@ -0,0 +1,137 @@
+package logs
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lamhai1401/gologs/logger"
+)
+
+func TestTyped(t *testing.T) {
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+
+	l := NewTyped("typed")
+	defer Forget("typed")
+	l.Debug("not written")
+	l.Info("packet forwarded", logger.String("stream_id", "a"), logger.Int("size", 1200))
+	// entries are written by log goroutines, wait to keep their order
+	waitPending()
+	l.Error("handler err", logger.Err(errTest))
+	waitPending()
+
+	got := out.String()
+	if !strings.Contains(got, "packet forwarded stream_id=a size=1200 seq=") || !strings.Contains(got, "handler err error=test") {
+		t.Errorf("output %q", got)
+	}
+	if strings.Contains(got, "not written") || strings.Contains(got, "caller=") {
+		t.Errorf("output %q", got)
+	}
+	if n := len(Recorder("typed").Entries()); n != 1 {
+		t.Errorf("expect the error dump to start over, got %d entries", n)
+	}
+}
+
+func TestTypedHookAsync(t *testing.T) {
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+	release := make(chan struct{})
+	remove := AddHook([]logger.Level{logger.InfoLevel}, func(*logger.Entry) error {
+		<-release
+		return nil
+	})
+	defer remove()
+
+	l := NewTyped("typed")
+	defer Forget("typed")
+	start := time.Now()
+	l.Info("hooked")
+	if time.Since(start) > 50*time.Millisecond {
+		t.Error("Info should not wait for hooks")
+	}
+	close(release)
+	waitPending()
+
+	// a new backend is used by the cached pipeline
+	other := &syncBuffer{}
+	SetLogger(NewLogging(other, "", 0))
+	l.Info("moved")
+	waitPending()
+	if !strings.Contains(out.String(), "hooked") || !strings.Contains(other.String(), "moved") {
+		t.Errorf("output %q then %q", out.String(), other.String())
+	}
+}
+
+func TestTypedClock(t *testing.T) {
+	out := &syncBuffer{}
+	defer withTestLog(out)()
+	l := NewLogging(out, "", LstdFlags|LUTC)
+	SetLogger(l)
+	// set after SetLogger, like a clock swapped in a running test
+	l.SetClock(logger.NewManualClock(time.Date(2020, 9, 10, 1, 2, 3, 0, time.UTC)))
+
+	typed := NewTyped("typed")
+	defer Forget("typed")
+	typed.Info("stamped")
+	waitPending()
+	if got := out.String(); !strings.HasPrefix(got, "2020/09/10 01:02:03 [INFO] stamped") {
+		t.Errorf("output %q", got)
+	}
+}
+
+type testError string
+
+func (e testError) Error() string { return string(e) }
+
+var errTest = testError("test")
+
+// withBenchLog write json entries to ioutil.Discard until the returned func is called
+func withBenchLog() func() {
+	old := backend
+	SetLogger(logger.NewWriterSink(ioutil.Discard, &logger.JSONEncoder{}))
+	return func() {
+		SetLogger(old)
+	}
+}
+
+func BenchmarkTypedInfo(b *testing.B) {
+	defer withBenchLog()()
+	l := NewTyped("bench")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("packet forwarded",
+			logger.String("stream_id", "1hGQ7ZLpJ3Cb6pY0xM0t8HX2Zqw"),
+			logger.Int("size", 1200),
+			logger.Duration("rtt", 35*time.Millisecond))
+	}
+}
+
+func BenchmarkTypedDebugDisabled(b *testing.B) {
+	defer withBenchLog()()
+	l := NewTyped("bench")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Debug("packet forwarded",
+			logger.String("stream_id", "1hGQ7ZLpJ3Cb6pY0xM0t8HX2Zqw"),
+			logger.Int("size", 1200),
+			logger.Duration("rtt", 35*time.Millisecond))
+	}
+}
+
+func BenchmarkNamedInfo(b *testing.B) {
+	defer withBenchLog()()
+	l := Named("bench")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.INFO("packet forwarded",
+			logger.String("stream_id", "1hGQ7ZLpJ3Cb6pY0xM0t8HX2Zqw"),
+			logger.Int("size", 1200),
+			logger.Duration("rtt", 35*time.Millisecond))
+	}
+	waitPending()
+}
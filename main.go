@@ -3,15 +3,17 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/segmentio/ksuid"
+	"github.com/lamhai1401/gologs/logger"
 )
 
 // GenerateID linter
 func GenerateID() string {
-	id := ksuid.New().String()
-	return id
+	return logger.GenerateID()
 }
 
 func test() {
@@ -56,7 +58,42 @@ func test() {
 	}
 }
 
+const usage = `usage: gologs <command> [flags]
+
+commands:
+  query  print entries of log files, see gologs query -h
+  ship   forward lines of log files to the log backend, see gologs ship -h
+`
+
+// interrupted return a channel closed on SIGINT or SIGTERM
+func interrupted() <-chan struct{} {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		close(stop)
+	}()
+	return stop
+}
+
 func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "query":
+		os.Exit(runQuery(os.Args[2:]))
+	case "ship":
+		os.Exit(runShip(os.Args[2:]))
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "gologs: unknown command %q\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
 }
 
 // func connectStomp() {
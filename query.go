@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lamhai1401/gologs/logger"
+)
+
+const queryUsage = `usage: gologs query [flags] file...
+
+Print entries of log files written by the file sink matching every filter.
+Lines are text, logfmt or json, files ending with .gz are decompressed.
+
+`
+
+// stringsFlag is a flag which can be repeated
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// runQuery run the query command and return the exit code
+func runQuery(args []string) int {
+	var where stringsFlag
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	level := fs.String("level", "", "min level: debug, info, warn, error, panic or fatal")
+	since := fs.String("since", "", "entries at or after: RFC3339, unix seconds or a duration before now (e.g. 1h)")
+	until := fs.String("until", "", "entries before, same format as -since")
+	fs.Var(&where, "where", "field expression, can be repeated:\nkey=value, key!=value, key~regexp, key!~regexp, key>n, key>=n, key<n, key<=n")
+	grep := fs.String("grep", "", "regexp matching the message or field values")
+	format := fs.String("format", "auto", "input format: auto, json, logfmt or text")
+	output := fs.String("o", "text", "output: text, json, logfmt or table")
+	follow := fs.Bool("f", false, "follow files like tail -F, across rotations and truncations")
+	rotated := fs.Bool("rotated", false, "read the rotated files of each file first, oldest first")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), queryUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	q, err := buildQuery(*level, *since, *until, where, *grep)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gologs:", err)
+		return 2
+	}
+	dec, err := logger.ParseDecoder(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gologs:", err)
+		return 2
+	}
+	out, err := newQueryOutput(*output, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gologs:", err)
+		return 2
+	}
+
+	code := 0
+	report := func(err error) {
+		fmt.Fprintln(os.Stderr, "gologs:", err)
+		code = 1
+	}
+	for _, path := range fs.Args() {
+		names := []string{path}
+		if *rotated {
+			names = logger.RotatedFiles(path)
+		}
+		if *follow && len(names) > 0 && names[len(names)-1] == path {
+			// path itself is read by its follower
+			names = names[:len(names)-1]
+		}
+		for _, name := range names {
+			if err := readLogFile(name, dec, &q, out); err != nil {
+				report(err)
+			}
+		}
+	}
+	if *follow {
+		out.follow = true
+		followLogFiles(fs.Args(), dec, &q, out, report)
+	}
+	if err := out.Flush(); err != nil {
+		report(err)
+	}
+	return code
+}
+
+// buildQuery return the query of the command flags
+func buildQuery(level, since, until string, where []string, grep string) (logger.StoreQuery, error) {
+	var q logger.StoreQuery
+	var err error
+	if level != "" {
+		if q.Level, err = logger.ParseLevel(level); err != nil {
+			return q, err
+		}
+	}
+	if q.Since, err = parseTimeFlag(since); err != nil {
+		return q, err
+	}
+	if q.Until, err = parseTimeFlag(until); err != nil {
+		return q, err
+	}
+	for _, expr := range where {
+		x, err := logger.ParseFieldExpr(expr)
+		if err != nil {
+			return q, err
+		}
+		q.Where = append(q.Where, x)
+	}
+	if grep != "" {
+		if q.Regexp, err = regexp.Compile(grep); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// parseTimeFlag parse RFC3339, unix seconds or a duration before now, empty is the zero time
+func parseTimeFlag(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q", s)
+	}
+	return t, nil
+}
+
+// readLogFile write matching entries of a file
+func readLogFile(name string, dec logger.Decoder, q *logger.StoreQuery, out *queryOutput) error {
+	r, err := logger.OpenLogFile(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if err := out.writeLine(scanner.Bytes(), dec, q); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// followLogFiles write matching entries appended to paths until interrupted
+func followLogFiles(paths []string, dec logger.Decoder, q *logger.StoreQuery, out *queryOutput, report func(error)) {
+	stop := interrupted()
+	lines := make(chan []byte, 100)
+	errs := make(chan error, len(paths))
+	var wg sync.WaitGroup
+	for _, path := range paths {
+		wg.Add(1)
+		go func(f *logger.Follower) {
+			defer wg.Done()
+			defer f.Close()
+			for {
+				line, err := f.Next(stop)
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					errs <- fmt.Errorf("%s: %w", f.Path(), err)
+					return
+				}
+				lines <- line
+			}
+		}(logger.NewFollower(path, 0, 250*time.Millisecond))
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	for line := range lines {
+		if err := out.writeLine(line, dec, q); err != nil {
+			report(err)
+		}
+	}
+	close(errs)
+	for err := range errs {
+		report(err)
+	}
+}
+
+// queryOutput write entries in a format
+type queryOutput struct {
+	enc    logger.Encoder    // nil for table
+	buf    *bufio.Writer     // buffered output of encoders
+	table  *tabwriter.Writer // table output
+	follow bool              // flush after every entry
+}
+
+func newQueryOutput(format string, w io.Writer) (*queryOutput, error) {
+	out := &queryOutput{buf: bufio.NewWriter(w)}
+	switch format {
+	case "text":
+		out.enc = &logger.TextEncoder{}
+	case "json":
+		out.enc = &logger.JSONEncoder{}
+	case "logfmt":
+		out.enc = &logger.LogfmtEncoder{}
+	case "table":
+		out.table = tabwriter.NewWriter(out.buf, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(out.table, "TIME\tLEVEL\tMESSAGE\tFIELDS")
+	default:
+		return nil, fmt.Errorf("unknown output %q", format)
+	}
+	return out, nil
+}
+
+// writeLine decode line and write it if it matches q
+func (o *queryOutput) writeLine(line []byte, dec logger.Decoder, q *logger.StoreQuery) error {
+	if len(line) == 0 {
+		return nil
+	}
+	e, err := dec.Decode(line)
+	if err != nil || !q.Match(e) {
+		return nil
+	}
+	if err := o.write(e); err != nil {
+		return err
+	}
+	if o.follow {
+		return o.Flush()
+	}
+	return nil
+}
+
+func (o *queryOutput) write(e *logger.Entry) error {
+	if o.table == nil {
+		b, err := o.enc.Encode(e)
+		if err != nil {
+			return err
+		}
+		_, err = o.buf.Write(b)
+		return err
+	}
+
+	fields := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		fields[i] = field.String()
+	}
+	row := []byte(e.Time.Format(time.RFC3339Nano) + "\t" + e.Level.String() + "\t")
+	row = logger.AppendEscaped(row, e.Message, false)
+	row = append(row, '\t')
+	row = logger.AppendEscaped(row, strings.Join(fields, " "), false)
+	row = append(row, '\n')
+	_, err := o.table.Write(row)
+	return err
+}
+
+// Flush write buffered entries
+func (o *queryOutput) Flush() error {
+	if o.table != nil {
+		if err := o.table.Flush(); err != nil {
+			return err
+		}
+	}
+	return o.buf.Flush()
+}
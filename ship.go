@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lamhai1401/gologs/logger"
+	"github.com/lamhai1401/gologs/logs"
+)
+
+const shipUsage = `usage: gologs ship [flags] file|glob...
+
+Follow log files like tail -F and write their entries to the log backend,
+configured like any program using logs: LOG_BACKEND (json, logfmt, text,
+journald, fluent or otlp), LOG_ROUTE_KEY, ... Offsets are saved after the
+backend is synced, a restart continue where the last run stopped.
+
+`
+
+// runShip run the ship command and return the exit code
+func runShip(args []string) int {
+	fs := flag.NewFlagSet("ship", flag.ContinueOnError)
+	format := fs.String("format", "auto", "input format: auto, json, logfmt or text")
+	offsets := fs.String("offsets", "gologs-ship.json", "file keeping the offset of each file, none if empty")
+	pathKey := fs.String("path-key", "file", "field with the path of the file, none if empty")
+	fromEnd := fs.Bool("from-end", false, "read files without a saved offset from their end, files created later are read from the start")
+	poll := fs.Duration("poll", 250*time.Millisecond, "check files for new lines every")
+	rescan := fs.Duration("rescan", 10*time.Second, "look for new files matching the globs every")
+	checkpoint := fs.Duration("checkpoint", time.Second, "save offsets every")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), shipUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	dec, err := logger.ParseDecoder(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gologs:", err)
+		return 2
+	}
+
+	s, err := logger.NewShipper(logger.ShipConfig{
+		Paths:      fs.Args(),
+		Decoder:    dec,
+		Out:        logs.Log,
+		Offsets:    *offsets,
+		PathKey:    *pathKey,
+		FromEnd:    *fromEnd,
+		Poll:       *poll,
+		Rescan:     *rescan,
+		Checkpoint: *checkpoint,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gologs:", err)
+		return 1
+	}
+	go func() {
+		for err := range s.Errors() {
+			fmt.Fprintln(os.Stderr, "gologs:", err)
+		}
+	}()
+	if err := s.Run(interrupted()); err != nil {
+		fmt.Fprintln(os.Stderr, "gologs:", err)
+		return 1
+	}
+	return 0
+}